@@ -14,8 +14,8 @@ const (
 )
 
 func initTestBucket(t *testing.T, b *Bucket) {
-	require.Nil(t, b.AddBucket("/opt:first", Nodes{{0, 1, 2}, {2, 3, 2}}))
-	require.Nil(t, b.AddBucket("/opt:second/sub:1", Nodes{{1, 2, 3}, {10, 6, 1}}))
+	require.Nil(t, b.AddBucket("/opt:first", Nodes{{N: 0, C: 1, P: 2}, {N: 2, C: 3, P: 2}}))
+	require.Nil(t, b.AddBucket("/opt:second/sub:1", Nodes{{N: 1, C: 2, P: 3}, {N: 10, C: 6, P: 1}}))
 
 	b.fillNodes()
 }
@@ -37,16 +37,50 @@ func TestNewWeightFunc(t *testing.T) {
 	copy(nodes, b.nodes)
 
 	expected := Nodes{
-		{10, 6, 1},
-		{2, 3, 2},
-		{1, 2, 3},
-		{0, 1, 2},
+		{N: 10, C: 6, P: 1},
+		{N: 2, C: 3, P: 2},
+		{N: 1, C: 2, P: 3},
+		{N: 0, C: 1, P: 2},
 	}
 
 	sort.Slice(nodes, func(i, j int) bool { return wf(nodes[i]) > wf(nodes[j]) })
 	require.Equal(t, expected, nodes)
 }
 
+func TestMetricWeightFunc(t *testing.T) {
+	var (
+		uptime = MetricWeightFunc("uptime")
+		space  = MetricWeightFunc("space")
+		n1     = Node{N: 1, M: NewMetrics(map[string]float64{"uptime": 0.5, "space": 100})}
+		n2     = Node{N: 2, M: NewMetrics(map[string]float64{"uptime": 1, "space": 50})}
+		n3     = Node{N: 3}
+	)
+
+	require.Equal(t, 0.5, uptime(n1))
+	require.Equal(t, 50.0, space(n2))
+	require.Equal(t, 0.0, uptime(n3))
+
+	t.Run("product", func(t *testing.T) {
+		wf := NewProductWeightFunc(
+			WeightTerm{Metric: uptime, Norm: NewMaxNorm(1)},
+			WeightTerm{Metric: space, Norm: NewMaxNorm(100)},
+		)
+		require.InEpsilon(t, 0.5, wf(n1), eps)
+		require.InEpsilon(t, 0.5, wf(n2), eps)
+		require.Equal(t, 0.0, wf(n3))
+	})
+
+	t.Run("weighted sum", func(t *testing.T) {
+		wf := NewSumWeightFunc(
+			WeightTerm{Metric: uptime, Norm: NewMaxNorm(1), Factor: 0.75},
+			WeightTerm{Metric: space, Norm: NewMaxNorm(100), Factor: 0.25},
+		)
+		require.InEpsilon(t, 0.625, wf(n1), eps)
+		require.InEpsilon(t, 0.875, wf(n2), eps)
+		require.Equal(t, 0.0, wf(n3))
+	})
+}
+
 func TestAggregator_Compute(t *testing.T) {
 	var (
 		b Bucket
@@ -164,11 +198,11 @@ func TestBucket_TraverseTree(t *testing.T) {
 
 	b := &Bucket{
 		children: []Bucket{
-			{nodes: Nodes{{0, 1, 2}, {2, 3, 2}}},
+			{nodes: Nodes{{N: 0, C: 1, P: 2}, {N: 2, C: 3, P: 2}}},
 			{
 				children: []Bucket{
-					{nodes: Nodes{{1, 2, 3}, {10, 6, 1}}},
-					{nodes: Nodes{{12, 3, 4}, {2, 3, 4}}},
+					{nodes: Nodes{{N: 1, C: 2, P: 3}, {N: 10, C: 6, P: 1}}},
+					{nodes: Nodes{{N: 12, C: 3, P: 4}, {N: 2, C: 3, P: 4}}},
 				},
 			},
 		},
@@ -0,0 +1,174 @@
+package netmap
+
+import (
+	"github.com/nspcc-dev/hrw"
+)
+
+type (
+	// Backups represents nodes selected in a single bucket together
+	// with ranked substitutes for them.
+	Backups struct {
+		// Path is the full path to the selected bucket.
+		Path string
+		// Primary contains nodes selected in the bucket in HRW order.
+		Primary Nodes
+		// Backups contains substitutes in HRW order. They belong to the same
+		// bucket or to its not selected siblings and satisfy the same filters.
+		Backups Nodes
+	}
+
+	pathBucket struct {
+		path string
+		b    Bucket
+	}
+)
+
+// FindNodesWithBackups returns the same nodes as FindNodes together with
+// up to k substitutes for every bucket selected by the last non-Node select.
+// Substitutes are the next nodes the same rule would pick, so they can be used
+// instead of the unavailable ones without recomputing placement: the rest
+// of the bucket's nodes in HRW order followed by nodes of the sibling buckets
+// which were not selected, in order of their rank. Sibling buckets have the same
+// key and are selected from the same parent, so the substitutes still satisfy
+// all filters and selects above the last one.
+// Nodes which are primary in any group are never used as substitutes.
+func (b *Bucket) FindNodesWithBackups(pivot []byte, k int, ss ...SFGroup) (Nodes, []Backups) {
+	var (
+		nodes  Nodes
+		ranked []Backups
+	)
+
+	for _, s := range ss {
+		m := b.GetMaxSelection(s)
+		if m == nil {
+			continue
+		}
+		g, r := m.getSelection(s.Selectors, pivot, m.newLimiter(s.Limits), "", nil)
+		if g == nil {
+			continue
+		}
+		nodes = merge(nodes, g.Nodelist())
+
+		for _, sel := range r.selected("") {
+			ranked = append(ranked, Backups{
+				Path:    sel.path,
+				Primary: sel.nodes,
+				Backups: m.substitutes(sel, r, pivot),
+			})
+		}
+	}
+
+	for i := range ranked {
+		var backups Nodes
+		for _, n := range ranked[i].Backups {
+			if len(backups) == k {
+				break
+			}
+			if search(nodes, n.N) == len(nodes) {
+				backups = append(backups, n)
+			}
+		}
+		ranked[i].Backups = backups
+	}
+	return nodes, ranked
+}
+
+// selectedBucket is a bucket selected by the last non-Node select.
+type selectedBucket struct {
+	ranking
+	parent string
+}
+
+// selected returns all buckets selected by the last non-Node select in rank order.
+// parent is the path of the bucket r was selected from.
+func (r ranking) selected(parent string) []selectedBucket {
+	if len(r.children) == 0 {
+		return []selectedBucket{{ranking: r, parent: parent}}
+	}
+
+	var bs []selectedBucket
+	for i := range r.children {
+		bs = append(bs, r.children[i].selected(r.path)...)
+	}
+	return bs
+}
+
+// contains checks if bucket located at path is selected in r.
+func (r ranking) contains(path string) bool {
+	if r.path == path {
+		return true
+	}
+	for i := range r.children {
+		if r.children[i].contains(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// substitutes returns candidates to replace primary nodes of sel:
+// the rest of sel's nodes followed by nodes of not selected siblings.
+// b is the maximal selection, r is the ranking of the whole selection.
+func (b *Bucket) substitutes(sel selectedBucket, r *ranking, pivot []byte) (nodes Nodes) {
+	c := b.getBucket(sel.path)
+	if c == nil {
+		return nil
+	}
+	nodes = orderNodes(c.Nodelist(), pivot)
+
+	p := b.getBucket(sel.parent)
+	if p == nil || sel.path == sel.parent {
+		return
+	}
+
+	siblings := getChildrenByKey(*p, Select{Key: c.Key}, sel.parent)
+	if len(pivot) != 0 {
+		p.sortByRank(siblings, hrw.Hash(pivot))
+	}
+	for i := range siblings {
+		if !r.contains(siblings[i].path) {
+			nodes = append(nodes, orderNodes(siblings[i].b.Nodelist(), pivot)...)
+		}
+	}
+	return
+}
+
+// Hash implements hrw.Hasher interface.
+func (p pathBucket) Hash() uint64 {
+	return p.b.Hash()
+}
+
+// collectKey returns all buckets with the specified key together with their paths.
+func (b Bucket) collectKey(key, prefix string) (bs []pathBucket) {
+	if b.Key == key {
+		return []pathBucket{{path: prefix, b: b}}
+	}
+	for _, c := range b.children {
+		bs = append(bs, c.collectKey(key, prefix+Separator+c.Name())...)
+	}
+	return
+}
+
+// getBucket returns sub-bucket of b located at path.
+func (b *Bucket) getBucket(path string) *Bucket {
+	if path == "" {
+		return b
+	}
+
+	var (
+		bs  = splitProps(path[len(Separator):])
+		cur = b
+	)
+
+loop:
+	for i := range bs {
+		for j := range cur.children {
+			if cur.children[j].Equals(bs[i]) {
+				cur = &cur.children[j]
+				continue loop
+			}
+		}
+		return nil
+	}
+	return cur
+}
@@ -0,0 +1,90 @@
+package netmap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBucket_FindNodesWithBackups(t *testing.T) {
+	root, err := newRoot(
+		bucket{"/Location:Europe/Country:Germany", []uint32{1, 2, 3, 4}},
+		bucket{"/Location:Europe/Country:Austria", []uint32{5, 6, 7}},
+		bucket{"/Location:Asia/Country:Korea", []uint32{8, 9, 10}},
+	)
+	require.NoError(t, err)
+
+	s := SFGroup{
+		Filters:   []Filter{{Key: "Location", F: FilterEQ("Europe")}},
+		Selectors: []Select{{Key: "Country", Count: 2}, {Key: NodesBucket, Count: 2}},
+		Exclude:   []uint32{4},
+	}
+
+	for _, pivot := range SamplePivots(10) {
+		nodes, bs := root.FindNodesWithBackups(pivot, 1, s)
+		require.Equal(t, root.FindNodes(pivot, s), nodes)
+		require.Len(t, bs, 2)
+
+		for _, b := range bs {
+			require.Len(t, b.Primary, 2)
+			require.Len(t, b.Backups, 1)
+			require.Subset(t, nodes, b.Primary)
+			require.NotContains(t, nodes, b.Backups[0])
+			require.NotEqual(t, uint32(4), b.Backups[0].N)
+
+			option := root.GetNodesByOption(b.Path)
+			require.Subset(t, option, b.Primary)
+			require.Subset(t, option, b.Backups)
+
+			// primary nodes and substitutes follow the selection ranking
+			var candidates Nodes
+			for _, n := range option {
+				if n.N != 4 {
+					candidates = append(candidates, n)
+				}
+			}
+			candidates = orderNodes(candidates, pivot)
+			require.Equal(t, candidates[:2], b.Primary)
+			require.Equal(t, candidates[2], b.Backups[0])
+
+			// backup must be the node selected when primary one is excluded
+			s1 := s
+			s1.Exclude = append([]uint32{b.Primary[0].N}, s.Exclude...)
+			require.Contains(t, root.FindNodes(pivot, s1), b.Backups[0])
+		}
+	}
+
+	t.Run("whole buckets", func(t *testing.T) {
+		s := SFGroup{Selectors: []Select{{Key: "Country", Count: 2}}}
+		for _, pivot := range SamplePivots(10) {
+			nodes, bs := root.FindNodesWithBackups(pivot, 2, s)
+			require.Len(t, bs, 2)
+
+			// the only not selected country is the fallback for both
+			var rest Nodes
+			for _, path := range []string{
+				"/Location:Europe/Country:Germany",
+				"/Location:Europe/Country:Austria",
+				"/Location:Asia/Country:Korea",
+			} {
+				if path != bs[0].Path && path != bs[1].Path {
+					rest = orderNodes(root.GetNodesByOption(path), pivot)
+				}
+			}
+			require.NotEmpty(t, rest)
+
+			for _, b := range bs {
+				require.ElementsMatch(t, root.GetNodesByOption(b.Path), b.Primary)
+				require.Subset(t, nodes, b.Primary)
+				require.Equal(t, rest[:2], b.Backups)
+			}
+		}
+	})
+
+	t.Run("unsatisfied", func(t *testing.T) {
+		s := SFGroup{Selectors: []Select{{Key: NodesBucket, Count: 11}}}
+		nodes, bs := root.FindNodesWithBackups(nil, 2, s)
+		require.Empty(t, nodes)
+		require.Empty(t, bs)
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"io/ioutil"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strconv"
 
 	"github.com/davecgh/go-spew/spew"
@@ -39,6 +40,33 @@ Example:
 [13 14]`,
 		Func: getSelection,
 	},
+	{
+		Name: "explain",
+		Help: "explain why current selection rules can't be satisfied",
+		LongHelp: `Usage: explain
+
+Example:
+>>> add 1 /Location:Europe/Country:Germany
+>>> add 2 /Location:Europe/Country:Austria
+>>> select 2 Country
+>>> filter Country NE Austria
+>>> explain
+selection failed: filter Country NE Austria left 1 node; select 2 Country needed 2 buckets under / but found 1`,
+		Func: explainSelection,
+	},
+	{
+		Name: "show-rule",
+		Help: "print current selection rules",
+		LongHelp: `Usage: show-rule
+
+Example:
+>>> select 2 Country
+>>> filter Location EQ Europe
+>>> show-rule
+SELECT 2 Country
+FILTER Location EQ Europe`,
+		Func: showRule,
+	},
 	{
 		Name:     "clear-selection",
 		Help:     "clear selection rules",
@@ -96,13 +124,15 @@ Example:
 	{
 		Name: "filter",
 		Help: "add FILTER placement rule",
-		LongHelp: `Usage: filter <key> <operation> <value>
-Operation can be one of EQ, NE, LT, LE, GT, GE
+		LongHelp: `Usage: filter <key> [NOT] <operation> <value> [type]
+Operation can be one of EQ, NE, LT, LE, GT, GE, LIKE, REGEX, PREFIX, SUFFIX
+Type can be one of String, Integer, Float, Version, Duration, Size
 
 Example:
 >>> add 1 /Location:Europe/Country:Germany
 >>> add 2 /Location:Europe/Country:Austria
 >>> filter Country NE Austria
+>>> filter City NOT LIKE San*
 `,
 		Func: addFilter,
 	},
@@ -136,16 +166,37 @@ func getState(c *ishell.Context) *state {
 	return c.Get(stateKey).(*state)
 }
 
+// isText checks if file contains netmap in text format.
+// Besides .txt, REPL scripts with .input extension are read as text.
+func isText(name string) bool {
+	switch filepath.Ext(name) {
+	case ".txt", ".input":
+		return true
+	}
+	return false
+}
+
 func read(b *netmap.Bucket, name string) error {
 	data, err := ioutil.ReadFile(name)
 	if err != nil {
 		return err
 	}
+	if isText(name) {
+		return b.UnmarshalText(data)
+	}
 	return b.UnmarshalBinary(data)
 }
 
 func write(b *netmap.Bucket, name string) error {
-	data, err := b.MarshalBinary()
+	var (
+		data []byte
+		err  error
+	)
+	if isText(name) {
+		data, err = b.MarshalText()
+	} else {
+		data, err = b.MarshalBinary()
+	}
 	if err != nil {
 		return err
 	}
@@ -164,6 +215,22 @@ func getSelection(c *ishell.Context) {
 	c.Println(nil)
 }
 
+func explainSelection(c *ishell.Context) {
+	s := getState(c)
+	if err := s.b.Explain(netmap.SFGroup{Selectors: s.ss, Filters: s.fs}); err != nil {
+		c.Println(err)
+		return
+	}
+	c.Println("selection can be satisfied")
+}
+
+func showRule(c *ishell.Context) {
+	s := getState(c)
+	c.Println(netmap.FormatRule(netmap.PlacementRule{
+		SFGroups: []netmap.SFGroup{{Selectors: s.ss, Filters: s.fs}},
+	}))
+}
+
 func clearSelection(c *ishell.Context) {
 	s := getState(c)
 	s.ss = nil
@@ -264,20 +331,44 @@ func addSelect(c *ishell.Context) {
 }
 
 func addFilter(c *ishell.Context) {
-	if len(c.Args) < 3 {
+	args := c.Args
+	if len(args) < 3 {
 		c.Err(errWrongFormat)
 		return
 	}
-	op, ok := netmap.Operation_value[c.Args[1]]
-	if !ok {
-		c.Err(errors.New("operation must be one of: EQ, NE, LT, LE, GT, GE"))
+	key, not := args[0], args[1] == netmap.Operation_NOT.String()
+	if args = args[1:]; not {
+		if args = args[1:]; len(args) < 2 {
+			c.Err(errWrongFormat)
+			return
+		}
+	}
+	op, ok := netmap.Operation_value[args[0]]
+	if !ok || op == int32(netmap.Operation_NP) || op == int32(netmap.Operation_OR) ||
+		op == int32(netmap.Operation_AND) || op == int32(netmap.Operation_NOT) {
+		c.Err(errors.New("operation must be one of: EQ, NE, LT, LE, GT, GE, LIKE, REGEX, PREFIX, SUFFIX"))
+		return
+	}
+	var typ int32
+	if len(args) > 2 {
+		if typ, ok = netmap.Type_value[args[2]]; !ok {
+			c.Err(errors.New("type must be one of: String, Integer, Float, Version, Duration, Size"))
+			return
+		}
+	}
+	f := netmap.Filter{
+		Key: key,
+		F:   netmap.NewTypedFilter(netmap.Operation(op), netmap.Type(typ), args[1]),
+	}
+	if not {
+		f.F = netmap.FilterNOT(f.F)
+	}
+	if err := f.Validate(); err != nil {
+		c.Err(err)
 		return
 	}
 	s := getState(c)
-	s.fs = append(s.fs, netmap.Filter{
-		Key: c.Args[0],
-		F:   netmap.NewFilter(netmap.Operation(op), c.Args[2]),
-	})
+	s.fs = append(s.fs, f)
 }
 
 func dotToPng(in, out string) error {
@@ -0,0 +1,119 @@
+package netmap
+
+import (
+	"sort"
+)
+
+type (
+	// BucketDiff represents difference between two netmaps.
+	BucketDiff struct {
+		Added   []NodeOptions
+		Removed Nodes
+		Moved   []NodeMove
+		Updated []NodeUpdate
+	}
+
+	// NodeOptions represents node together with all of its options.
+	NodeOptions struct {
+		Node    Node
+		Options []string
+	}
+
+	// NodeMove represents node which options have changed.
+	NodeMove struct {
+		N    uint32
+		From []string
+		To   []string
+	}
+
+	// NodeUpdate represents node which capacity, price or metrics have changed.
+	NodeUpdate struct {
+		From Node
+		To   Node
+	}
+)
+
+// Diff returns difference between old netmap o and new netmap n.
+func Diff(o, n Bucket) (d BucketDiff) {
+	var (
+		on, nn = o.Nodelist(), n.Nodelist()
+		oo, no = o.nodeOptions(), n.nodeOptions()
+	)
+
+	for i, j := 0, 0; i < len(on) || j < len(nn); {
+		switch true {
+		case j == len(nn) || (i < len(on) && on[i].N < nn[j].N):
+			d.Removed = append(d.Removed, on[i])
+			i++
+		case i == len(on) || on[i].N > nn[j].N:
+			d.Added = append(d.Added, NodeOptions{Node: nn[j], Options: no[nn[j].N]})
+			j++
+		default:
+			if !equalOptions(oo[on[i].N], no[nn[j].N]) {
+				d.Moved = append(d.Moved, NodeMove{
+					N:    nn[j].N,
+					From: oo[on[i].N],
+					To:   no[nn[j].N],
+				})
+			}
+			if on[i] != nn[j] {
+				d.Updated = append(d.Updated, NodeUpdate{From: on[i], To: nn[j]})
+			}
+			i++
+			j++
+		}
+	}
+	return
+}
+
+// Empty checks if d contains no changes.
+func (d BucketDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Moved) == 0 && len(d.Updated) == 0
+}
+
+// GetOptionsByNode returns list of options of node n.
+// Every option is a full path to the deepest bucket containing n.
+func (b Bucket) GetOptionsByNode(n uint32) []string {
+	return b.nodeOptions()[n]
+}
+
+func (b Bucket) nodeOptions() map[uint32][]string {
+	opts := make(map[uint32][]string)
+	b.collectOptions("", opts)
+	return opts
+}
+
+func (b Bucket) collectOptions(prefix string, opts map[uint32][]string) {
+	var nodes Nodes
+
+	for _, c := range b.children {
+		c.collectOptions(prefix+Separator+c.Name(), opts)
+		nodes = merge(nodes, c.Nodelist())
+	}
+
+	if prefix == "" {
+		return
+	}
+	for _, n := range b.Nodelist() {
+		if search(nodes, n.N) == len(nodes) {
+			opts[n.N] = append(opts[n.N], prefix)
+		}
+	}
+}
+
+func equalOptions(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	a = append([]string(nil), a...)
+	b = append([]string(nil), b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
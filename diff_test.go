@@ -0,0 +1,73 @@
+package netmap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBucket_GetOptionsByNode(t *testing.T) {
+	root, err := newRoot(
+		bucket{"/Location:Europe/Country:Germany/City:Berlin", []uint32{1, 2}},
+		bucket{"/Location:Europe/Country:Austria", []uint32{3}},
+		bucket{"/Trust:10", []uint32{1, 3}},
+	)
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"/Location:Europe/Country:Germany/City:Berlin", "/Trust:10"}, root.GetOptionsByNode(1))
+	require.Equal(t, []string{"/Location:Europe/Country:Germany/City:Berlin"}, root.GetOptionsByNode(2))
+	require.Equal(t, []string{"/Location:Europe/Country:Austria", "/Trust:10"}, root.GetOptionsByNode(3))
+	require.Nil(t, root.GetOptionsByNode(4))
+}
+
+func TestDiff(t *testing.T) {
+	var (
+		o, n Bucket
+		d    BucketDiff
+		err  error
+	)
+
+	o, err = newStrawRoot(
+		strawBucket{"/Location:Europe/Country:Germany", Nodes{{N: 1, C: 1}, {N: 2, C: 2}}},
+		strawBucket{"/Location:Europe/Country:Austria", Nodes{{N: 3, C: 3}}},
+		strawBucket{"/Location:Asia/Country:Japan", Nodes{{N: 4, C: 4}}},
+	)
+	require.NoError(t, err)
+
+	d = Diff(o, o.Copy())
+	require.True(t, d.Empty())
+
+	n, err = newStrawRoot(
+		strawBucket{"/Location:Europe/Country:Germany", Nodes{{N: 1, C: 1}}},
+		strawBucket{"/Location:Europe/Country:Austria", Nodes{{N: 2, C: 2}, {N: 3, C: 30, P: 1}}},
+		strawBucket{"/Location:Asia/Country:Korea", Nodes{{N: 5, C: 5}}},
+	)
+	require.NoError(t, err)
+
+	d = Diff(o, n)
+	require.False(t, d.Empty())
+	require.Equal(t, []NodeOptions{{Node: Node{N: 5, C: 5}, Options: []string{"/Location:Asia/Country:Korea"}}}, d.Added)
+	require.Equal(t, Nodes{{N: 4, C: 4}}, d.Removed)
+	require.Equal(t, []NodeMove{{
+		N:    2,
+		From: []string{"/Location:Europe/Country:Germany"},
+		To:   []string{"/Location:Europe/Country:Austria"},
+	}}, d.Moved)
+	require.Equal(t, []NodeUpdate{{From: Node{N: 3, C: 3}, To: Node{N: 3, C: 30, P: 1}}}, d.Updated)
+
+	t.Run("metrics change", func(t *testing.T) {
+		n, err = newStrawRoot(
+			strawBucket{"/Location:Europe/Country:Germany", Nodes{{N: 1, C: 1, M: NewMetrics(map[string]float64{"uptime": 1})}, {N: 2, C: 2}}},
+			strawBucket{"/Location:Europe/Country:Austria", Nodes{{N: 3, C: 3}}},
+			strawBucket{"/Location:Asia/Country:Japan", Nodes{{N: 4, C: 4}}},
+		)
+		require.NoError(t, err)
+
+		d = Diff(o, n)
+		require.Empty(t, d.Added)
+		require.Empty(t, d.Removed)
+		require.Empty(t, d.Moved)
+		require.Len(t, d.Updated, 1)
+		require.Equal(t, uint32(1), d.Updated[0].To.N)
+	})
+}
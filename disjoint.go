@@ -0,0 +1,77 @@
+package netmap
+
+import (
+	"github.com/pkg/errors"
+)
+
+// FindNodesDisjoint returns list of nodes, corresponding to specified placement rule,
+// where every group is placed on nodes not used by other groups if key is NodesBucket,
+// or in buckets with key not used by other groups otherwise.
+// Groups are processed in order, every next one avoids nodes or buckets
+// selected by the previous ones.
+// Error is returned if some group can't be satisfied.
+func (b *Bucket) FindNodesDisjoint(pivot []byte, key string, ss ...SFGroup) (Nodes, error) {
+	gs, err := b.findDisjoint(pivot, key, ss)
+	if err != nil {
+		return nil, err
+	}
+
+	var nodes Nodes
+	for _, g := range gs {
+		nodes = merge(nodes, g.Nodelist())
+	}
+	return nodes, nil
+}
+
+// FindGraphDisjoint returns random subgraph, corresponding to specified placement rule,
+// with the same disjointness constraints as in FindNodesDisjoint.
+func (b *Bucket) FindGraphDisjoint(pivot []byte, key string, ss ...SFGroup) (*Bucket, error) {
+	gs, err := b.findDisjoint(pivot, key, ss)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Bucket{Key: b.Key, Value: b.Value}
+	for _, g := range gs {
+		if c.CheckConflicts(*g) {
+			return nil, errors.New("selected subgraphs conflict")
+		}
+		c.Merge(*g)
+	}
+	return c, nil
+}
+
+func (b *Bucket) findDisjoint(pivot []byte, key string, ss []SFGroup) ([]*Bucket, error) {
+	if key == "" {
+		return nil, errors.New("empty key")
+	}
+
+	var (
+		gs      = make([]*Bucket, 0, len(ss))
+		nodes   []uint32
+		buckets []string
+	)
+
+	for i, s := range ss {
+		s.Exclude = append(append([]uint32(nil), s.Exclude...), nodes...)
+		s.ExcludeBuckets = append(append([]string(nil), s.ExcludeBuckets...), buckets...)
+
+		g := b.findGraph(pivot, s)
+		if g == nil {
+			if rs := b.explain(i, s); len(rs) != 0 {
+				return nil, errors.Wrapf(SelectionError(rs), "can't select disjoint group %d", i)
+			}
+			return nil, errors.Errorf("can't select disjoint group %d", i)
+		}
+		gs = append(gs, g)
+
+		if key == NodesBucket {
+			nodes = append(nodes, g.Nodelist().Nodes()...)
+			continue
+		}
+		for _, c := range g.collectKey(key, "") {
+			buckets = append(buckets, c.path)
+		}
+	}
+	return gs, nil
+}
@@ -0,0 +1,69 @@
+package netmap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBucket_FindNodesDisjoint(t *testing.T) {
+	root, err := newRoot(
+		bucket{"/Location:Europe/Country:Germany", []uint32{1, 2}},
+		bucket{"/Location:Europe/Country:Austria", []uint32{3, 4}},
+		bucket{"/Location:Europe/Country:France", []uint32{5, 6}},
+		bucket{"/Location:Asia/Country:Korea", []uint32{7, 8}},
+	)
+	require.NoError(t, err)
+
+	europe := SFGroup{
+		Filters:   []Filter{{Key: "Location", F: FilterEQ("Europe")}},
+		Selectors: []Select{{Key: "Country", Count: 1}, {Key: NodesBucket, Count: 1}},
+	}
+
+	t.Run("disjoint nodes", func(t *testing.T) {
+		for _, pivot := range SamplePivots(20) {
+			nodes, err := root.FindNodesDisjoint(pivot, NodesBucket, europe, europe, europe)
+			require.NoError(t, err)
+			require.Len(t, nodes, 3)
+		}
+
+		all := SFGroup{Selectors: []Select{{Key: NodesBucket, Count: 8}}}
+		_, err := root.FindNodesDisjoint(nil, NodesBucket, europe, all)
+		require.EqualError(t, err, "can't select disjoint group 1: selection failed: "+
+			"node 1 excluded; "+
+			"select 8 Node needed 8 nodes under / but found 7")
+	})
+
+	t.Run("disjoint buckets", func(t *testing.T) {
+		for _, pivot := range SamplePivots(20) {
+			nodes, err := root.FindNodesDisjoint(pivot, "Country", europe, europe, europe)
+			require.NoError(t, err)
+			require.Len(t, nodes, 3)
+
+			countries := make(map[string]bool)
+			for _, n := range nodes {
+				opts := root.GetOptionsByNode(n.N)
+				require.Len(t, opts, 1)
+				countries[opts[0]] = true
+			}
+			require.Len(t, countries, 3)
+
+			g, err := root.FindGraphDisjoint(pivot, "Country", europe, europe)
+			require.NoError(t, err)
+			require.Len(t, g.Nodelist(), 2)
+			require.Len(t, g.Children(), 1)
+			require.Len(t, g.Children()[0].Children(), 2)
+		}
+
+		_, err := root.FindNodesDisjoint(nil, "Country", europe, europe, europe, europe)
+		require.Error(t, err)
+
+		_, err = root.FindGraphDisjoint(nil, "Location", europe, europe)
+		require.Error(t, err)
+	})
+
+	t.Run("empty key", func(t *testing.T) {
+		_, err := root.FindNodesDisjoint(nil, "", europe)
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,144 @@
+package netmap
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ReasonKind is a kind of placement failure reason.
+type ReasonKind int
+
+const (
+	// FilterReason shows how many nodes are left after applying filter.
+	FilterReason ReasonKind = iota
+	// ExcludeReason shows that node or bucket was excluded explicitly.
+	ExcludeReason
+	// SelectReason shows that there are not enough buckets to select.
+	SelectReason
+	// LimitReason shows how many nodes of the bucket can be selected
+	// without exceeding limits.
+	LimitReason
+)
+
+type (
+	// Reason describes single step of placement rule evaluation
+	// which contributed to the selection failure.
+	Reason struct {
+		Kind ReasonKind
+		// Group is the index of SFGroup in placement rule.
+		Group int
+		// Path is the full path to the bucket where select has failed
+		// for SelectReason, where limits were exceeded for LimitReason
+		// and path to the excluded bucket for ExcludeReason.
+		Path string
+		// Filter is set for FilterReason.
+		Filter Filter
+		// Select is set for SelectReason.
+		Select Select
+		// Node is set for ExcludeReason if single node was excluded.
+		Node uint32
+		// Count is the number of nodes left for FilterReason,
+		// the number of buckets found for SelectReason,
+		// the number of nodes which can be selected for LimitReason and
+		// the number of nodes excluded together with bucket for ExcludeReason.
+		Count uint32
+	}
+
+	// SelectionError contains reasons why placement rule can't be satisfied.
+	SelectionError []Reason
+)
+
+func (r Reason) String() string {
+	switch r.Kind {
+	case FilterReason:
+		return fmt.Sprintf("filter %s left %s", formatFilter(r.Filter), plural(r.Count, "node"))
+	case ExcludeReason:
+		if r.Path != "" {
+			return fmt.Sprintf("bucket %s excluded %s", r.Path, plural(r.Count, "node"))
+		}
+		return fmt.Sprintf("node %d excluded", r.Node)
+	case LimitReason:
+		return fmt.Sprintf("limits left %s under %s", plural(r.Count, "node"), pathOrRoot(r.Path))
+	default:
+		path, what := pathOrRoot(r.Path), "bucket"
+		if r.Select.Key == NodesBucket {
+			what = "node"
+		}
+		return fmt.Sprintf("select %d %s needed %s under %s but found %d",
+			r.Select.Count, r.Select.Key, plural(r.Select.Count, what), path, r.Count)
+	}
+}
+
+func (e SelectionError) Error() string {
+	ss := make([]string, 0, len(e))
+	for i := range e {
+		ss = append(ss, e[i].String())
+	}
+	return "selection failed: " + strings.Join(ss, "; ")
+}
+
+// Explain checks if every group of placement rule ss can be satisfied in b.
+// If some of them can't, SelectionError is returned, which
+// contains filter and exclusion results together with failed selects
+// and exceeded limits of every unsatisfiable group.
+func (b Bucket) Explain(ss ...SFGroup) error {
+	var e SelectionError
+	for i := range ss {
+		e = append(e, b.explain(i, ss[i])...)
+	}
+	if len(e) != 0 {
+		return e
+	}
+	return nil
+}
+
+func (b Bucket) explain(group int, s SFGroup) []Reason {
+	var (
+		rs      []Reason
+		sel     []Reason
+		allowed = b.nodes
+	)
+
+	for _, f := range s.Filters {
+		allowed = intersect(allowed, b.findAllowed([]Filter{f}))
+		rs = append(rs, Reason{Kind: FilterReason, Group: group, Filter: f, Count: uint32(len(allowed))})
+	}
+	for _, n := range s.Exclude {
+		if search(allowed, n) != len(allowed) {
+			rs = append(rs, Reason{Kind: ExcludeReason, Group: group, Node: n})
+		}
+	}
+	for _, o := range s.ExcludeBuckets {
+		if n := len(intersect(allowed, b.findBucketNodes(o))); n != 0 {
+			rs = append(rs, Reason{Kind: ExcludeReason, Group: group, Path: o, Count: uint32(n)})
+		}
+	}
+
+	r, _ := b.getMaxSelectionC(s.Selectors, excludeFunc(allowed, b.excluded(s)), b.newLimiter(s.Limits), true, "", &sel)
+	if r != nil {
+		// limits are checked in every bucket separately while building
+		// the maximal selection, so the selection itself can still exceed them;
+		// reasons collected so far are about buckets missing in r
+		if g, _ := r.getSelection(s.Selectors, nil, r.newLimiter(s.Limits), "", &sel); g != nil {
+			return nil
+		}
+	}
+	for i := range sel {
+		sel[i].Group = group
+	}
+	return append(rs, sel...)
+}
+
+func plural(n uint32, s string) string {
+	if n == 1 {
+		return "1 " + s
+	}
+	return fmt.Sprintf("%d %ss", n, s)
+}
+
+func pathOrRoot(path string) string {
+	if path == "" {
+		return Separator
+	}
+	return path
+}
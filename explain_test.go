@@ -0,0 +1,75 @@
+package netmap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBucket_Explain(t *testing.T) {
+	root, err := newRoot(
+		bucket{"/Location:Europe/Country:Germany/City:Berlin", []uint32{1, 2}},
+		bucket{"/Location:Europe/Country:Austria/City:Vienna", []uint32{3, 4}},
+		bucket{"/Location:Europe/Country:France/City:Paris", []uint32{5}},
+		bucket{"/Location:Asia/Country:Korea/City:Seoul", []uint32{6, 7}},
+	)
+	require.NoError(t, err)
+
+	ok := SFGroup{Selectors: []Select{{Key: "Country", Count: 2}}}
+	require.NoError(t, root.Explain(ok))
+
+	t.Run("filter", func(t *testing.T) {
+		s := SFGroup{
+			Filters:   []Filter{{Key: "Country", F: FilterNE("Austria")}, {Key: "Location", F: FilterEQ("Europe")}},
+			Selectors: []Select{{Key: "Country", Count: 3}},
+		}
+		require.Nil(t, root.GetMaxSelection(s))
+
+		err := root.Explain(ok, s)
+		require.Equal(t, SelectionError{
+			{Kind: FilterReason, Group: 1, Filter: s.Filters[0], Count: 5},
+			{Kind: FilterReason, Group: 1, Filter: s.Filters[1], Count: 3},
+			{Kind: SelectReason, Group: 1, Select: s.Selectors[0], Count: 2},
+		}, err)
+		require.EqualError(t, err, "selection failed: "+
+			"filter Country NE Austria left 5 nodes; "+
+			"filter Location EQ Europe left 3 nodes; "+
+			"select 3 Country needed 3 buckets under / but found 2")
+	})
+
+	t.Run("exclude", func(t *testing.T) {
+		s := SFGroup{
+			Selectors: []Select{{Key: "Location", Count: 1}, {Key: "City", Count: 2}, {Key: NodesBucket, Count: 2}},
+			Exclude:   []uint32{1, 8},
+		}
+		require.Nil(t, root.GetMaxSelection(s))
+		require.EqualError(t, root.Explain(s), "selection failed: "+
+			"node 1 excluded; "+
+			"select 1 Location needed 1 bucket under / but found 0; "+
+			"select 2 City needed 2 buckets under /Location:Europe but found 1; "+
+			"select 2 Node needed 2 nodes under /Location:Europe/Country:Germany/City:Berlin but found 1; "+
+			"select 2 Node needed 2 nodes under /Location:Europe/Country:France/City:Paris but found 1; "+
+			"select 2 City needed 2 buckets under /Location:Asia but found 1")
+	})
+
+	t.Run("exclude bucket", func(t *testing.T) {
+		s := SFGroup{
+			Selectors:      []Select{{Key: "Country", Count: 3}},
+			ExcludeBuckets: []string{"/Country:Germany", "/Location:Asia"},
+		}
+		require.EqualError(t, root.Explain(s), "selection failed: "+
+			"bucket /Country:Germany excluded 2 nodes; "+
+			"bucket /Location:Asia excluded 2 nodes; "+
+			"select 3 Country needed 3 buckets under / but found 2")
+	})
+
+	t.Run("composite filter", func(t *testing.T) {
+		s := SFGroup{
+			Filters:   []Filter{{Key: "City", F: FilterIn("Berlin", "Paris")}},
+			Selectors: []Select{{Key: "City", Count: 3}},
+		}
+		require.EqualError(t, root.Explain(s), "selection failed: "+
+			"filter City IN (Berlin, Paris) left 3 nodes; "+
+			"select 3 City needed 3 buckets under / but found 2")
+	})
+}
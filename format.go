@@ -0,0 +1,156 @@
+package netmap
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// FormatRule returns canonical representation of r in placement policy language.
+// Every clause is written on a separate line, groups are separated by ';'.
+// Result of FormatRule can be parsed back with ParseRule if every group
+// of r has at least one SELECT and all counts and limits are positive.
+// Other rules, e.g. with filter-only groups, are formatted too,
+// but ParseRule rejects them.
+func FormatRule(r PlacementRule) string {
+	var (
+		lines  []string
+		groups = make([]string, 0, len(r.SFGroups))
+	)
+
+	if r.ReplFactor != 0 {
+		lines = append(lines, "REP "+strconv.FormatUint(uint64(r.ReplFactor), 10))
+	}
+	for i := range r.SFGroups {
+		groups = append(groups, formatGroup(r.SFGroups[i]))
+	}
+	if len(groups) != 0 {
+		lines = append(lines, strings.Join(groups, ";\n"))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func formatGroup(g SFGroup) string {
+	var lines []string
+
+	for _, s := range g.Selectors {
+		lines = append(lines, "SELECT "+strconv.FormatUint(uint64(s.Count), 10)+" "+quoteIdent(s.Key))
+	}
+	for _, f := range g.Filters {
+		lines = append(lines, "FILTER "+formatFilter(f))
+	}
+	if len(g.Exclude)+len(g.ExcludeBuckets) != 0 {
+		items := make([]string, 0, len(g.Exclude)+len(g.ExcludeBuckets))
+		for _, n := range g.Exclude {
+			items = append(items, strconv.FormatUint(uint64(n), 10))
+		}
+		for _, o := range g.ExcludeBuckets {
+			items = append(items, quoteIdent(o))
+		}
+		lines = append(lines, "EXCLUDE "+strings.Join(items, ", "))
+	}
+	for _, l := range g.Limits {
+		lines = append(lines, "LIMIT "+strconv.FormatUint(uint64(l.Max), 10)+" PER "+quoteIdent(l.Key))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatFilter returns f in placement policy language.
+func formatFilter(f Filter) string {
+	if f.F == nil {
+		return quoteIdent(f.Key)
+	}
+	return formatSimpleFilter(quoteIdent(f.Key), *f.F, false)
+}
+
+// formatSimpleFilter returns sf applied to key. If nested is set,
+// OR and AND expressions are parenthesized.
+func formatSimpleFilter(key string, sf SimpleFilter, nested bool) string {
+	args := sf.GetFArgs()
+
+	switch sf.Op {
+	case Operation_OR, Operation_AND, Operation_NOT:
+		if args == nil || len(args.Filters) == 0 {
+			return key + " " + sf.Op.String()
+		}
+	default:
+		if args != nil {
+			return key + " " + sf.Op.String()
+		}
+	}
+
+	switch sf.Op {
+	case Operation_NP:
+		return key + " " + sf.Op.String()
+	case Operation_OR, Operation_AND:
+		if values, ok := inValues(sf); ok {
+			return key + " IN (" + strings.Join(values, ", ") + ")"
+		}
+
+		ss := make([]string, 0, len(args.Filters))
+		for i := range args.Filters {
+			ss = append(ss, formatSimpleFilter(key, args.Filters[i], true))
+		}
+		s := strings.Join(ss, " "+sf.Op.String()+" ")
+		if nested {
+			return "(" + s + ")"
+		}
+		return s
+	case Operation_NOT:
+		if len(args.Filters) == 1 {
+			arg := args.Filters[0]
+			if values, ok := inValues(arg); ok {
+				return key + " NOT IN (" + strings.Join(values, ", ") + ")"
+			}
+			if arg.GetFArgs() == nil && !isComposite(arg.Op) {
+				return key + " NOT " + formatCondition(arg)
+			}
+			if arg.Op == Operation_NOT {
+				return "NOT " + formatSimpleFilter(key, arg, false)
+			}
+			return "NOT " + formatSimpleFilter(key, arg, true)
+		}
+		return "NOT " + formatSimpleFilter(key, SimpleFilter{Op: Operation_AND, Args: sf.Args}, true)
+	}
+
+	return key + " " + formatCondition(sf)
+}
+
+// formatCondition returns operation, operand and type of simple sf.
+func formatCondition(sf SimpleFilter) string {
+	s := sf.Op.String() + " " + quoteIdent(sf.GetValue())
+	if sf.Type != Type_String {
+		s += " AS " + sf.Type.String()
+	}
+	return s
+}
+
+// inValues returns values of sf if it can be written with IN.
+func inValues(sf SimpleFilter) ([]string, bool) {
+	args := sf.GetFArgs()
+	if sf.Op != Operation_OR || args == nil || len(args.Filters) == 0 {
+		return nil, false
+	}
+
+	values := make([]string, 0, len(args.Filters))
+	for _, f := range args.Filters {
+		if f.Op != Operation_EQ || f.Type != Type_String || f.GetFArgs() != nil {
+			return nil, false
+		}
+		values = append(values, quoteIdent(f.GetValue()))
+	}
+	return values, true
+}
+
+// quoteIdent quotes s if it can't be parsed as a single word.
+func quoteIdent(s string) string {
+	if s == "" || keywords[s] {
+		return strconv.Quote(s)
+	}
+	for _, r := range s {
+		if unicode.IsSpace(r) || !unicode.IsPrint(r) || strings.ContainsRune("(),;\"#", r) {
+			return strconv.Quote(s)
+		}
+	}
+	return s
+}
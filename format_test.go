@@ -0,0 +1,100 @@
+package netmap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatRule(t *testing.T) {
+	r := PlacementRule{
+		ReplFactor: 4,
+		SFGroups: []SFGroup{
+			{
+				Selectors: []Select{{Count: 2, Key: "Country"}, {Count: 1, Key: "City"}},
+				Filters: []Filter{
+					{Key: "Location", F: FilterEQ("Europe")},
+					{Key: "Country", F: FilterNotIn("Russia", "Great Britain")},
+					{Key: "City", F: FilterNOT(FilterIn("Moscow", "SELECT"))},
+					{Key: "Trust", F: FilterAND(FilterGE(10), FilterOR(FilterLE(20), FilterEQ("100")))},
+				},
+				Exclude:        []uint32{7, 8},
+				ExcludeBuckets: []string{"/Location:Europe/City:San Francisco"},
+				Limits:         []Limit{{Key: "City", Max: 1}},
+			},
+			{
+				Selectors: []Select{{Count: 1, Key: NodesBucket}},
+				Filters: []Filter{
+					{Key: "City", F: FilterNOT(FilterLike("San*"))},
+					{Key: "City", F: FilterNOT(FilterOR(FilterRegex("^(a|b)$"), FilterPrefix("New ")))},
+					{Key: "Version", F: FilterNOT(FilterNOT(NewTypedFilter(Operation_GE, Type_Version, "v1.2.0")))},
+					{Key: CapacityFilterKey, F: FilterOR(
+						NewTypedFilter(Operation_GE, Type_Size, "1TB"),
+						FilterOR(FilterEQ("0"), FilterEQ("1")),
+					)},
+				},
+			},
+		},
+	}
+
+	expected := `REP 4
+SELECT 2 Country
+SELECT 1 City
+FILTER Location EQ Europe
+FILTER Country NE Russia AND Country NE "Great Britain"
+FILTER City NOT IN (Moscow, "SELECT")
+FILTER Trust GE 10 AND (Trust LE 20 OR Trust EQ 100)
+EXCLUDE 7, 8, "/Location:Europe/City:San Francisco"
+LIMIT 1 PER City;
+SELECT 1 Node
+FILTER City NOT LIKE San*
+FILTER NOT (City REGEX "^(a|b)$" OR City PREFIX "New ")
+FILTER NOT Version NOT GE v1.2.0 AS Version
+FILTER Node.C GE 1TB AS Size OR Node.C IN (0, 1)`
+	require.Equal(t, expected, FormatRule(r))
+
+	parsed, err := ParseRule(FormatRule(r))
+	require.NoError(t, err)
+	require.Equal(t, r, parsed)
+
+	t.Run("round trip", func(t *testing.T) {
+		texts := []string{
+			`REP 3 SELECT 2 Country FROM Location EQ Europe SELECT 1 City FILTER Trust GE 10`,
+			`SELECT 1 City FILTER (City EQ Berlin OR City REGEX "^San (Jose|Diego)$") AND NOT City PREFIX "New "`,
+			`SELECT 1 A FILTER A EQ 1 AND B EQ 2 AND A NE 3; SELECT 2 B EXCLUDE /A:1, 5; SELECT 1 Node LIMIT 2 PER B`,
+			`SELECT 1 A FILTER A EQ 1 AND (A EQ 2 AND A EQ 3) OR (A EQ 4 OR A EQ 5)`,
+		}
+		for _, text := range texts {
+			r, err := ParseRule(text)
+			require.NoError(t, err, text)
+
+			s := FormatRule(r)
+			parsed, err := ParseRule(s)
+			require.NoError(t, err, s)
+			require.Equal(t, r, parsed, s)
+			require.Equal(t, s, FormatRule(parsed))
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		require.Equal(t, "", FormatRule(PlacementRule{}))
+		require.Equal(t, "REP 2", FormatRule(PlacementRule{ReplFactor: 2}))
+	})
+
+	t.Run("filter-only group", func(t *testing.T) {
+		r := PlacementRule{ReplFactor: 2, SFGroups: []SFGroup{{
+			Filters: []Filter{{Key: "Location", F: FilterEQ("Europe")}},
+		}}}
+		require.NoError(t, r.Validate())
+
+		s := FormatRule(r)
+		require.Equal(t, "REP 2\nFILTER Location EQ Europe", s)
+		_, err := ParseRule(s)
+		require.EqualError(t, err, "line 2, column 1: group without SELECT")
+
+		r.SFGroups[0].Selectors = []Select{{Count: 1, Key: NodesBucket}}
+		parsed, err := ParseRule(FormatRule(r))
+		require.NoError(t, err)
+		require.Equal(t, r, parsed)
+	})
+}
@@ -0,0 +1,178 @@
+package netmap
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// jsonBucket is the JSON representation of Bucket.
+	jsonBucket struct {
+		Key      string
+		Value    string
+		Weight   float64      `json:",omitempty"`
+		Nodes    Nodes        `json:",omitempty"`
+		Children []jsonBucket `json:",omitempty"`
+	}
+
+	// jsonNode is the JSON representation of Node.
+	// Metrics are written as an object.
+	jsonNode struct {
+		N uint32
+		C uint64
+		P uint64
+		M map[string]float64 `json:",omitempty"`
+	}
+
+	// jsonPlacementRule is used to decode PlacementRule
+	// without recursing into UnmarshalJSON.
+	jsonPlacementRule PlacementRule
+
+	// jsonSimpleFilter is the JSON representation of SimpleFilter.
+	// Operation and type are written by name, type is omitted for String.
+	// Either Value or Filters is set depending on the kind of arguments.
+	jsonSimpleFilter struct {
+		Op      string
+		Type    string          `json:",omitempty"`
+		Value   *string         `json:",omitempty"`
+		Filters *[]SimpleFilter `json:",omitempty"`
+	}
+)
+
+// MarshalJSON implements the json.Marshaler interface.
+func (n Node) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonNode{N: n.N, C: n.C, P: n.P, M: n.M.Map()})
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (n *Node) UnmarshalJSON(data []byte) error {
+	var jn jsonNode
+	if err := json.Unmarshal(data, &jn); err != nil {
+		return err
+	}
+
+	*n = Node{N: jn.N, C: jn.C, P: jn.P, M: NewMetrics(jn.M)}
+	return nil
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+// Whole tree is written including nodes and weights of all sub-buckets.
+func (b Bucket) MarshalJSON() ([]byte, error) {
+	return json.Marshal(newJSONBucket(b))
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+// Decoded tree is checked with Validate.
+func (b *Bucket) UnmarshalJSON(data []byte) error {
+	var jb jsonBucket
+	if err := json.Unmarshal(data, &jb); err != nil {
+		return err
+	}
+
+	nb := jb.bucket()
+	if err := nb.Validate(); err != nil {
+		return err
+	}
+	*b = nb
+	return nil
+}
+
+func newJSONBucket(b Bucket) jsonBucket {
+	jb := jsonBucket{
+		Key:    b.Key,
+		Value:  b.Value,
+		Weight: b.weight,
+		Nodes:  b.nodes,
+	}
+	if len(b.children) != 0 {
+		jb.Children = make([]jsonBucket, 0, len(b.children))
+		for i := range b.children {
+			jb.Children = append(jb.Children, newJSONBucket(b.children[i]))
+		}
+	}
+	return jb
+}
+
+func (jb jsonBucket) bucket() Bucket {
+	b := Bucket{
+		Key:    jb.Key,
+		Value:  jb.Value,
+		weight: jb.Weight,
+		nodes:  jb.Nodes,
+	}
+	if len(jb.Children) != 0 {
+		b.children = make([]Bucket, 0, len(jb.Children))
+		for i := range jb.Children {
+			b.children = append(b.children, jb.Children[i].bucket())
+		}
+	}
+	return b
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+// Decoded rule is checked with Validate.
+func (r *PlacementRule) UnmarshalJSON(data []byte) error {
+	var jr jsonPlacementRule
+	if err := json.Unmarshal(data, &jr); err != nil {
+		return err
+	}
+
+	nr := PlacementRule(jr)
+	if err := nr.Validate(); err != nil {
+		return err
+	}
+	*r = nr
+	return nil
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (sf SimpleFilter) MarshalJSON() ([]byte, error) {
+	jf := jsonSimpleFilter{Op: sf.Op.String()}
+	if sf.Type != Type_String {
+		jf.Type = sf.Type.String()
+	}
+
+	switch args := sf.Args.(type) {
+	case *SimpleFilter_Value:
+		jf.Value = &args.Value
+	case *SimpleFilter_FArgs:
+		fs := []SimpleFilter{}
+		if args.FArgs != nil {
+			fs = args.FArgs.Filters
+		}
+		jf.Filters = &fs
+	}
+	return json.Marshal(jf)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (sf *SimpleFilter) UnmarshalJSON(data []byte) error {
+	var jf jsonSimpleFilter
+	if err := json.Unmarshal(data, &jf); err != nil {
+		return err
+	}
+
+	op, ok := Operation_value[jf.Op]
+	if !ok {
+		return errors.Errorf("unknown operation %q", jf.Op)
+	}
+
+	var typ int32
+	if jf.Type != "" {
+		if typ, ok = Type_value[jf.Type]; !ok {
+			return errors.Errorf("unknown type %q", jf.Type)
+		}
+	}
+
+	*sf = SimpleFilter{Op: Operation(op), Type: Type(typ)}
+	switch {
+	case jf.Value != nil && jf.Filters != nil:
+		return errors.New("both value and filters are set")
+	case jf.Value != nil:
+		sf.Args = &SimpleFilter_Value{Value: *jf.Value}
+	case jf.Filters != nil:
+		sf.Args = &SimpleFilter_FArgs{FArgs: &SimpleFilters{Filters: *jf.Filters}}
+	}
+	return nil
+}
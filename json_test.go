@@ -0,0 +1,136 @@
+package netmap
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBucket_MarshalJSON(t *testing.T) {
+	b, err := newStrawRoot(
+		strawBucket{"/Location:Europe/Country:Germany", Nodes{
+			{N: 1, C: 10, P: 1, M: NewMetrics(map[string]float64{"uptime": 0.99})},
+			{N: 2, C: 20},
+		}},
+		strawBucket{"/Location:Asia", Nodes{{N: 3, C: 30, P: 3}}},
+	)
+	require.NoError(t, err)
+
+	data, err := json.Marshal(Bucket{Key: "Location", Value: "Asia", weight: 0.5, nodes: Nodes{{N: 3, C: 30, P: 3}}})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"Key":"Location","Value":"Asia","Weight":0.5,"Nodes":[{"N":3,"C":30,"P":3}]}`, string(data))
+
+	b.children[0].weight = 0.7
+	b.children[1].weight = 0.3
+
+	data, err = json.Marshal(b)
+	require.NoError(t, err)
+
+	var nb Bucket
+	require.NoError(t, json.Unmarshal(data, &nb))
+	require.Equal(t, b, nb)
+	require.Equal(t, 0.7, nb.children[0].weight)
+	require.Equal(t, map[string]float64{"uptime": 0.99}, nb.Nodelist()[0].M.Map())
+	require.NoError(t, nb.Validate())
+
+	t.Run("several options", func(t *testing.T) {
+		data, err := ioutil.ReadFile("testdata/legacy")
+		require.NoError(t, err)
+
+		var b Bucket
+		require.NoError(t, b.UnmarshalBinary(data))
+		require.NotEmpty(t, b.GetOptionsByNode(1))
+
+		data, err = json.Marshal(b)
+		require.NoError(t, err)
+
+		var nb Bucket
+		require.NoError(t, json.Unmarshal(data, &nb))
+		require.Equal(t, b, nb)
+
+		b = Bucket{}
+		require.NoError(t, b.AddNode(1, "/Location:Europe/Country:DE", "/Trust:10"))
+		data, err = json.Marshal(b)
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(data, &nb))
+		require.Equal(t, b, nb)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		data, err := json.Marshal(Bucket{})
+		require.NoError(t, err)
+		require.JSONEq(t, `{"Key":"","Value":""}`, string(data))
+
+		var nb Bucket
+		require.NoError(t, json.Unmarshal(data, &nb))
+		require.Equal(t, Bucket{}, nb)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		var nb Bucket
+		require.Error(t, json.Unmarshal([]byte(`{"Nodes":1}`), &nb))
+		require.EqualError(t, json.Unmarshal([]byte(`{"Nodes":[{"N":2},{"N":1}]}`), &nb),
+			"invalid bucket: /: node 1: nodes are not sorted")
+		require.EqualError(t, json.Unmarshal([]byte(`{"Nodes":[{"N":1}],"Children":[{"Key":"City","Nodes":[{"N":1}]}]}`), &nb),
+			"invalid bucket: /City:: empty value")
+		require.Equal(t, Bucket{}, nb)
+	})
+}
+
+func TestPlacementRule_JSON(t *testing.T) {
+	r := PlacementRule{
+		ReplFactor: 2,
+		SFGroups: []SFGroup{{
+			Selectors: []Select{{Count: 1, Key: "City"}},
+			Filters: []Filter{
+				{Key: "City", F: FilterNOT(FilterIn("Moscow", ""))},
+				{Key: CapacityFilterKey, F: NewTypedFilter(Operation_GE, Type_Size, "1TB")},
+				{Key: "Any", F: &SimpleFilter{Op: Operation_NP}},
+			},
+			Exclude:        []uint32{5},
+			ExcludeBuckets: []string{"/Country:Russia"},
+			Limits:         []Limit{{Key: "Country", Max: 1}},
+		}},
+	}
+
+	data, err := json.Marshal(r)
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"ReplFactor": 2,
+		"SFGroups": [{
+			"Selectors": [{"Count": 1, "Key": "City"}],
+			"Filters": [
+				{"Key": "City", "F": {"Op": "NOT", "Filters": [
+					{"Op": "OR", "Filters": [{"Op": "EQ", "Value": "Moscow"}, {"Op": "EQ", "Value": ""}]}
+				]}},
+				{"Key": "Node.C", "F": {"Op": "GE", "Type": "Size", "Value": "1TB"}},
+				{"Key": "Any", "F": {"Op": "NP"}}
+			],
+			"Exclude": [5],
+			"ExcludeBuckets": ["/Country:Russia"],
+			"Limits": [{"Key": "Country", "Max": 1}]
+		}]
+	}`, string(data))
+
+	var nr PlacementRule
+	require.NoError(t, json.Unmarshal(data, &nr))
+	require.Equal(t, r, nr)
+
+	t.Run("invalid", func(t *testing.T) {
+		var sf SimpleFilter
+		require.EqualError(t, json.Unmarshal([]byte(`{"Op":"XOR"}`), &sf), `unknown operation "XOR"`)
+		require.EqualError(t, json.Unmarshal([]byte(`{"Op":"EQ","Type":"Bool"}`), &sf), `unknown type "Bool"`)
+		require.Error(t, json.Unmarshal([]byte(`{"Op":"EQ","Value":"1","Filters":[]}`), &sf))
+
+		var r PlacementRule
+		require.EqualError(t, json.Unmarshal([]byte(`{"SFGroups":[{"Selectors":[{"Count":0,"Key":"City"}]}]}`), &r),
+			"group 0: select City: count must be positive")
+		require.EqualError(t, json.Unmarshal([]byte(`{"SFGroups":[{"Filters":[{"Key":"City","F":{"Op":"NOT"}}]}]}`), &r),
+			"group 0: filter City NOT: NOT without arguments")
+		require.EqualError(t, json.Unmarshal([]byte(`{"SFGroups":[{"ExcludeBuckets":["/"]}]}`), &r),
+			`group 0: exclude "/": root bucket is not allowed`)
+		require.Equal(t, PlacementRule{}, r)
+	})
+}
@@ -0,0 +1,89 @@
+package netmap
+
+// limiter tracks number of nodes selected in every bucket restricted by limits.
+type limiter struct {
+	limits  []Limit
+	domains []map[uint32]string
+	counts  []map[string]uint32
+}
+
+// newLimiter returns limiter for ls with domains of nodes taken from b.
+// Limits with zero Max are ignored. If there are no limits left, nil is returned.
+func (b Bucket) newLimiter(ls []Limit) *limiter {
+	var valid []Limit
+	for i := range ls {
+		if ls[i].Max != 0 {
+			valid = append(valid, ls[i])
+		}
+	}
+	if ls = valid; len(ls) == 0 {
+		return nil
+	}
+
+	l := &limiter{
+		limits:  ls,
+		domains: make([]map[uint32]string, len(ls)),
+		counts:  make([]map[string]uint32, len(ls)),
+	}
+	for i := range ls {
+		l.domains[i] = make(map[uint32]string)
+		l.counts[i] = make(map[string]uint32)
+		for _, c := range b.collectKey(ls[i].Key, "") {
+			for _, n := range c.b.Nodelist() {
+				l.domains[i][n.N] = c.path
+			}
+		}
+	}
+	return l
+}
+
+// fits checks if n can be selected without exceeding any of the limits.
+func (l *limiter) fits(n Node) bool {
+	for i := range l.limits {
+		if d, ok := l.domains[i][n.N]; ok && l.counts[i][d] >= l.limits[i].Max {
+			return false
+		}
+	}
+	return true
+}
+
+// add tries to select all nodes. If some of them doesn't fit,
+// nothing is selected and false is returned.
+func (l *limiter) add(nodes ...Node) bool {
+	for i := range nodes {
+		if !l.fits(nodes[i]) {
+			l.remove(nodes[:i]...)
+			return false
+		}
+		l.update(nodes[i], 1)
+	}
+	return true
+}
+
+// capacity returns the number of nodes which can be selected together
+// without exceeding limits. Nodes are tried in order, nothing remains selected.
+func (l *limiter) capacity(nodes Nodes) uint32 {
+	var selected Nodes
+	for i := range nodes {
+		if l.add(nodes[i]) {
+			selected = append(selected, nodes[i])
+		}
+	}
+	l.remove(selected...)
+	return uint32(len(selected))
+}
+
+// remove deselects nodes.
+func (l *limiter) remove(nodes ...Node) {
+	for i := range nodes {
+		l.update(nodes[i], -1)
+	}
+}
+
+func (l *limiter) update(n Node, delta int) {
+	for i := range l.limits {
+		if d, ok := l.domains[i][n.N]; ok {
+			l.counts[i][d] = uint32(int(l.counts[i][d]) + delta)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package netmap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBucket_GetSelectionWithLimits(t *testing.T) {
+	root, err := newRoot(
+		bucket{"/Location:Europe/Country:Germany/City:Berlin", []uint32{1, 2, 3}},
+		bucket{"/Location:Europe/Country:Germany/City:Hamburg", []uint32{4, 5}},
+		bucket{"/Location:Europe/Country:Germany/City:Munich", []uint32{6}},
+		bucket{"/Location:Europe/Country:Austria/City:Vienna", []uint32{7, 8}},
+		bucket{"/Location:Europe/Country:Austria/City:Graz", []uint32{9}},
+		bucket{"/Location:Asia/Country:Korea/City:Seoul", []uint32{10, 11}},
+	)
+	require.NoError(t, err)
+
+	s := SFGroup{
+		Selectors: []Select{{Key: NodesBucket, Count: 3}},
+		Limits:    []Limit{{Key: "City", Max: 1}, {Key: "Country", Max: 2}},
+	}
+
+	check := func(t *testing.T, nodes Nodes, maxCountry int) {
+		cities := make(map[string]int)
+		countries := make(map[string]int)
+		for _, n := range nodes {
+			opts := root.GetOptionsByNode(n.N)
+			require.Len(t, opts, 1)
+			cities[opts[0]]++
+			countries[opts[0][:len("/Location:Europe/Country:Germany")]]++
+		}
+		for _, c := range cities {
+			require.Equal(t, 1, c)
+		}
+		for _, c := range countries {
+			require.True(t, c <= maxCountry)
+		}
+	}
+
+	for _, pivot := range SamplePivots(30) {
+		nodes := root.FindNodes(pivot, s)
+		require.Len(t, nodes, 3)
+		check(t, nodes, 2)
+
+		g := root.GetSelectionWithLimits(s.Selectors, s.Limits, pivot)
+		require.NotNil(t, g)
+		require.Equal(t, nodes, g.Nodelist())
+
+		// limits in nested selects are counted across all buckets
+		ls := []Limit{{Key: "City", Max: 1}}
+		g = root.GetSelectionWithLimits([]Select{{Key: "Location", Count: 1}, {Key: NodesBucket, Count: 3}}, ls, pivot)
+		require.NotNil(t, g)
+		require.Len(t, g.Nodelist(), 3)
+		check(t, g.Nodelist(), 3)
+		require.Equal(t, "Europe", g.Children()[0].Value)
+	}
+
+	t.Run("whole buckets", func(t *testing.T) {
+		ls := []Limit{{Key: "City", Max: 2}}
+		g := root.GetSelectionWithLimits([]Select{{Key: "City", Count: 5}}, ls, nil)
+		require.NotNil(t, g)
+		require.Equal(t, []uint32{4, 5, 6, 7, 8, 9, 10, 11}, g.Nodelist().Nodes())
+
+		require.Nil(t, root.GetSelectionWithLimits([]Select{{Key: "City", Count: 6}}, ls, nil))
+	})
+
+	t.Run("zero max", func(t *testing.T) {
+		s := SFGroup{
+			Selectors: []Select{{Key: NodesBucket, Count: 11}},
+			Limits:    []Limit{{Key: "City"}},
+		}
+		require.Len(t, root.FindNodes(nil, s), 11)
+		require.Error(t, s.Validate())
+	})
+
+	t.Run("unsatisfiable", func(t *testing.T) {
+		s.Selectors[0].Count = 6
+		require.Empty(t, root.FindNodes(nil, s))
+		require.Nil(t, root.FindGraph(nil, s))
+		require.Nil(t, root.GetMaxSelection(s))
+		require.EqualError(t, root.Explain(s), "selection failed: "+
+			"limits left 5 nodes under /; "+
+			"select 6 Node needed 6 nodes under / but found 5")
+
+		_, err := root.Place(PlacementRule{ReplFactor: 1, SFGroups: []SFGroup{s}}, nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "limits left 5 nodes")
+
+		// whole buckets exceeding limits can't be selected
+		ws := SFGroup{
+			Selectors: []Select{{Key: "City", Count: 6}},
+			Limits:    []Limit{{Key: "City", Max: 2}},
+		}
+		require.Nil(t, root.GetMaxSelection(ws))
+		require.Contains(t, root.Explain(ws).Error(), "limits left 0 nodes under /Location:Europe/Country:Germany/City:Berlin")
+
+		// without limits the same selection is possible
+		s.Limits = nil
+		require.Len(t, root.FindNodes(nil, s), 6)
+	})
+
+	t.Run("limit over sibling buckets", func(t *testing.T) {
+		b, err := newRoot(
+			bucket{"/Country:DE/City:Berlin", []uint32{1}},
+			bucket{"/Country:DE/City:Munich", []uint32{2}},
+			bucket{"/Country:FR/City:Paris", []uint32{3}},
+		)
+		require.NoError(t, err)
+
+		s := SFGroup{
+			Selectors: []Select{{Key: "Country", Count: 1}, {Key: "City", Count: 2}},
+			Limits:    []Limit{{Key: "Country", Max: 1}},
+		}
+		require.Empty(t, b.FindNodes(nil, s))
+		require.EqualError(t, b.Explain(s), "selection failed: "+
+			"select 2 City needed 2 buckets under /Country:FR but found 1; "+
+			"select 1 Country needed 1 bucket under / but found 0; "+
+			"select 2 City needed 2 buckets under /Country:DE but found 1; "+
+			"limits left 0 nodes under /Country:DE/City:Munich")
+
+		_, err = b.Place(PlacementRule{ReplFactor: 1, SFGroups: []SFGroup{s}}, nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "limits left 0 nodes under /Country:DE/City:Munich")
+
+		s.Limits[0].Max = 2
+		require.Equal(t, []uint32{1, 2}, b.FindNodes(nil, s).Nodes())
+		require.NoError(t, b.Explain(s))
+	})
+}
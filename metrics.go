@@ -0,0 +1,129 @@
+package netmap
+
+import (
+	"encoding/binary"
+	"math"
+	"sort"
+)
+
+// Metrics is an immutable set of named numeric node metrics.
+// Metrics are stored in canonical form, so they can be compared with ==
+// and copied freely: two sets with the same names and values are equal.
+// Zero value is an empty set.
+type Metrics struct {
+	// enc contains [lnName][Name][Value] for every metric sorted by name,
+	// where lnName is uint32 and Value is float64 bits, both in big-endian.
+	enc string
+}
+
+// NewMetrics returns Metrics containing all values of m.
+func NewMetrics(m map[string]float64) Metrics {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var (
+		buf []byte
+		tmp [8]byte
+	)
+	for _, name := range names {
+		binary.BigEndian.PutUint32(tmp[:], uint32(len(name)))
+		buf = append(buf, tmp[:4]...)
+		buf = append(buf, name...)
+		binary.BigEndian.PutUint64(tmp[:], math.Float64bits(canonical(m[name])))
+		buf = append(buf, tmp[:]...)
+	}
+	return Metrics{enc: string(buf)}
+}
+
+// canonical returns v with negative zero replaced by zero,
+// so that equal values have equal encoding.
+func canonical(v float64) float64 {
+	if v == 0 {
+		return 0
+	}
+	return v
+}
+
+// each calls f for every metric in order of names.
+// Iteration stops if f returns false.
+func (m Metrics) each(f func(name string, v float64) bool) {
+	for s := m.enc; len(s) != 0; {
+		ln := int(uintAt(s, 4))
+		name := s[4 : 4+ln]
+		v := math.Float64frombits(uintAt(s[4+ln:], 8))
+		s = s[4+ln+8:]
+		if !f(name, v) {
+			return
+		}
+	}
+}
+
+// uintAt decodes big-endian unsigned integer from the first size bytes of s.
+func uintAt(s string, size int) (r uint64) {
+	for i := 0; i < size; i++ {
+		r = r<<8 | uint64(s[i])
+	}
+	return
+}
+
+// Get returns value of metric name and whether it is present.
+func (m Metrics) Get(name string) (float64, bool) {
+	var (
+		res   float64
+		found bool
+	)
+	m.each(func(n string, v float64) bool {
+		if n == name {
+			res, found = v, true
+		}
+		return !found && n < name
+	})
+	return res, found
+}
+
+// Len returns the number of metrics in m.
+func (m Metrics) Len() int {
+	var l int
+	m.each(func(string, float64) bool {
+		l++
+		return true
+	})
+	return l
+}
+
+// Names returns sorted names of all metrics in m.
+func (m Metrics) Names() []string {
+	var names []string
+	m.each(func(name string, _ float64) bool {
+		names = append(names, name)
+		return true
+	})
+	return names
+}
+
+// Map returns new map containing all metrics of m or nil if m is empty.
+func (m Metrics) Map() map[string]float64 {
+	if len(m.enc) == 0 {
+		return nil
+	}
+
+	res := make(map[string]float64)
+	m.each(func(name string, v float64) bool {
+		res[name] = v
+		return true
+	})
+	return res
+}
+
+// With returns copy of m with metric name set to v.
+func (m Metrics) With(name string, v float64) Metrics {
+	res := m.Map()
+	if res == nil {
+		res = make(map[string]float64, 1)
+	}
+	res[name] = v
+	return NewMetrics(res)
+}
@@ -0,0 +1,44 @@
+package netmap
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics(t *testing.T) {
+	var empty Metrics
+	require.Zero(t, empty.Len())
+	require.Nil(t, empty.Map())
+	require.Equal(t, empty, NewMetrics(nil))
+	require.Equal(t, empty, NewMetrics(map[string]float64{}))
+
+	m := NewMetrics(map[string]float64{"uptime": 0.9, "load": 0.5, "": -1})
+	require.Equal(t, 3, m.Len())
+	require.Equal(t, []string{"", "load", "uptime"}, m.Names())
+	require.Equal(t, map[string]float64{"uptime": 0.9, "load": 0.5, "": -1}, m.Map())
+
+	v, ok := m.Get("load")
+	require.True(t, ok)
+	require.Equal(t, 0.5, v)
+	_, ok = m.Get("bandwidth")
+	require.False(t, ok)
+
+	t.Run("comparable", func(t *testing.T) {
+		n := Node{N: 1, C: 2, P: 3, M: m}
+		require.True(t, n == Node{N: 1, C: 2, P: 3, M: NewMetrics(map[string]float64{"": -1, "load": 0.5, "uptime": 0.9})})
+		require.False(t, n == Node{N: 1, C: 2, P: 3})
+		require.True(t, NewMetrics(map[string]float64{"a": 0}) == NewMetrics(map[string]float64{"a": math.Copysign(0, -1)}))
+	})
+
+	t.Run("immutable", func(t *testing.T) {
+		mm := m.Map()
+		mm["uptime"] = 0
+		require.Equal(t, 0.9, Node{M: m}.Metric("uptime"))
+
+		w := m.With("uptime", 1).With("bandwidth", 100)
+		require.Equal(t, 0.9, Node{M: m}.Metric("uptime"))
+		require.Equal(t, map[string]float64{"uptime": 1, "load": 0.5, "": -1, "bandwidth": 100}, w.Map())
+	})
+}
@@ -0,0 +1,75 @@
+package netmap
+
+import (
+	"encoding/binary"
+)
+
+// Movement represents amount of data which moves between two netmaps.
+type Movement struct {
+	// Total is the number of pivots checked.
+	Total int
+	// Changed is the number of pivots which placement has changed.
+	Changed int
+	// Inbound maps node to the number of pivots newly placed on it.
+	Inbound map[uint32]int
+	// Outbound maps node to the number of pivots no longer placed on it.
+	Outbound map[uint32]int
+}
+
+// SamplePivots returns count deterministic pivots which can be used
+// instead of real object IDs.
+func SamplePivots(count int) [][]byte {
+	pivots := make([][]byte, 0, count)
+	for i := 0; i < count; i++ {
+		pivot := make([]byte, 8)
+		binary.BigEndian.PutUint64(pivot, uint64(i))
+		pivots = append(pivots, pivot)
+	}
+	return pivots
+}
+
+// EstimateMovement compares placement of every pivot in netmaps o and n
+// according to placement rule ss.
+func EstimateMovement(o, n Bucket, pivots [][]byte, ss ...SFGroup) Movement {
+	m := Movement{
+		Total:    len(pivots),
+		Inbound:  make(map[uint32]int),
+		Outbound: make(map[uint32]int),
+	}
+
+	for _, pivot := range pivots {
+		var (
+			on      = o.FindNodes(pivot, ss...)
+			nn      = n.FindNodes(pivot, ss...)
+			changed bool
+		)
+
+		for i, j := 0, 0; i < len(on) || j < len(nn); {
+			switch true {
+			case j == len(nn) || (i < len(on) && on[i].N < nn[j].N):
+				m.Outbound[on[i].N]++
+				changed = true
+				i++
+			case i == len(on) || on[i].N > nn[j].N:
+				m.Inbound[nn[j].N]++
+				changed = true
+				j++
+			default:
+				i++
+				j++
+			}
+		}
+		if changed {
+			m.Changed++
+		}
+	}
+	return m
+}
+
+// Fraction returns fraction of pivots which placement has changed.
+func (m Movement) Fraction() float64 {
+	if m.Total == 0 {
+		return 0
+	}
+	return float64(m.Changed) / float64(m.Total)
+}
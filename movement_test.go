@@ -0,0 +1,62 @@
+package netmap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimateMovement(t *testing.T) {
+	var (
+		o, n   Bucket
+		m      Movement
+		err    error
+		pivots = SamplePivots(1000)
+		ss     = SFGroup{Selectors: []Select{
+			{Key: "Country", Count: 1},
+			{Key: NodesBucket, Count: 2},
+		}}
+	)
+
+	buckets := []bucket{
+		{"/Location:Europe/Country:Germany", []uint32{1, 2, 3}},
+		{"/Location:Europe/Country:Austria", []uint32{4, 5, 6}},
+		{"/Location:Asia/Country:Japan", []uint32{7, 8, 9}},
+	}
+
+	o, err = newRoot(buckets...)
+	require.NoError(t, err)
+
+	require.Len(t, pivots, 1000)
+	require.Equal(t, pivots, SamplePivots(1000))
+
+	m = EstimateMovement(o, o.Copy(), pivots, ss)
+	require.Equal(t, 1000, m.Total)
+	require.Equal(t, 0, m.Changed)
+	require.Equal(t, 0.0, m.Fraction())
+	require.Empty(t, m.Inbound)
+	require.Empty(t, m.Outbound)
+
+	n, err = newRoot(append(buckets, bucket{"/Location:Asia/Country:Korea", []uint32{10, 11, 12}})...)
+	require.NoError(t, err)
+
+	m = EstimateMovement(o, n, pivots, ss)
+	require.True(t, m.Changed > 0)
+	require.True(t, m.Fraction() < 0.5)
+	for node := range m.Inbound {
+		require.Contains(t, []uint32{10, 11, 12}, node)
+	}
+	for node := range m.Outbound {
+		require.NotContains(t, []uint32{10, 11, 12}, node)
+	}
+
+	var in, out int
+	for _, c := range m.Inbound {
+		in += c
+	}
+	for _, c := range m.Outbound {
+		out += c
+	}
+	require.Equal(t, in, out)
+	require.Equal(t, 2*m.Changed, in)
+}
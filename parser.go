@@ -0,0 +1,586 @@
+package netmap
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Placement policy language describes PlacementRule in human-readable form:
+//
+//	REP 3
+//	SELECT 2 Country FROM Location EQ Europe
+//	SELECT 1 City
+//	FILTER Trust GE 10 AND Trust LE 20
+//	FILTER City NOT IN (Moscow, "San Francisco")
+//	EXCLUDE 7, /Country:Russia
+//	LIMIT 1 PER City;
+//	SELECT 1 Node FILTER Node.C GE 1TB AS Size
+//
+// Rule starts with optional REP clause setting replication factor
+// followed by groups separated by ';'. If REP is omitted, ReplFactor is 0.
+// Every group consists of clauses and must contain at least one SELECT:
+//   - SELECT <count> <key> [FROM <filter>] adds selector (and filter);
+//   - FILTER <filter> adds filter;
+//   - EXCLUDE <item>, ... excludes nodes by number or buckets by path;
+//   - LIMIT <max> PER <key> adds limit.
+//
+// Filter is a combination of <key> [NOT] <op> <value> [AS <type>] and <key> [NOT] IN (<value>, ...)
+// expressions with AND, OR, NOT and parentheses. OR and NOT can be applied
+// only to expressions with the same key. Keywords are case-sensitive,
+// values which contain spaces or special characters or coincide with keywords
+// must be double-quoted.
+
+const (
+	tokWord = iota
+	tokString
+	tokPunct
+	tokEOF
+)
+
+type (
+	// ParseError describes error in placement policy text.
+	ParseError struct {
+		// Line and Column are 1-based position of the error.
+		Line   int
+		Column int
+		Msg    string
+	}
+
+	position struct {
+		line, column int
+	}
+
+	token struct {
+		kind int
+		text string
+		pos  position
+	}
+
+	parser struct {
+		toks []token
+		i    int
+	}
+
+	// filterExpr is a parsed filter expression. If key is empty,
+	// expression contains conjunction of parts with different keys.
+	filterExpr struct {
+		pos   position
+		key   string
+		sf    *SimpleFilter
+		parts []*filterExpr
+	}
+)
+
+var keywords = map[string]bool{
+	"REP": true, "SELECT": true, "FROM": true, "FILTER": true, "EXCLUDE": true,
+	"LIMIT": true, "PER": true, "AND": true, "OR": true, "NOT": true, "IN": true, "AS": true,
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Msg)
+}
+
+func errorAt(pos position, format string, args ...interface{}) *ParseError {
+	return &ParseError{Line: pos.line, Column: pos.column, Msg: fmt.Sprintf(format, args...)}
+}
+
+// ParseRule parses placement policy text into PlacementRule.
+// Returned error is *ParseError.
+func ParseRule(s string) (PlacementRule, error) {
+	var r PlacementRule
+
+	toks, err := tokenize(s)
+	if err != nil {
+		return r, err
+	}
+
+	p := &parser{toks: toks}
+	if p.isKeyword("REP") {
+		p.next()
+		if r.ReplFactor, err = p.parsePositive("replication factor"); err != nil {
+			return r, err
+		}
+	}
+
+	for {
+		g, err := p.parseGroup()
+		if err != nil {
+			return r, err
+		}
+		r.SFGroups = append(r.SFGroups, g)
+
+		if p.peek().kind == tokEOF {
+			return r, nil
+		}
+		if err = p.expectPunct(";"); err != nil {
+			return r, err
+		}
+		if p.peek().kind == tokEOF {
+			return r, nil
+		}
+	}
+}
+
+func tokenize(s string) ([]token, error) {
+	var (
+		toks []token
+		pos  = position{line: 1, column: 1}
+	)
+
+	advance := func(r rune) {
+		if r == '\n' {
+			pos.line++
+			pos.column = 1
+		} else {
+			pos.column++
+		}
+	}
+
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case unicode.IsSpace(r):
+			advance(r)
+			i += size
+		case r == '#':
+			for i < len(s) && s[i] != '\n' {
+				_, size = utf8.DecodeRuneInString(s[i:])
+				pos.column++
+				i += size
+			}
+		case strings.ContainsRune("(),;", r):
+			toks = append(toks, token{kind: tokPunct, text: string(r), pos: pos})
+			advance(r)
+			i += size
+		case r == '"':
+			start, j := pos, i+1
+			for ; j < len(s) && s[j] != '"' && s[j] != '\n'; j++ {
+				if s[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(s) || s[j] != '"' {
+				return nil, errorAt(start, "unterminated string")
+			}
+			text, err := strconv.Unquote(s[i : j+1])
+			if err != nil {
+				return nil, errorAt(start, "invalid string %s", s[i:j+1])
+			}
+			toks = append(toks, token{kind: tokString, text: text, pos: start})
+			for _, c := range s[i : j+1] {
+				advance(c)
+			}
+			i = j + 1
+		default:
+			start, j := pos, i
+			for j < len(s) {
+				c, n := utf8.DecodeRuneInString(s[j:])
+				if unicode.IsSpace(c) || strings.ContainsRune("(),;\"#", c) {
+					break
+				}
+				advance(c)
+				j += n
+			}
+			toks = append(toks, token{kind: tokWord, text: s[i:j], pos: start})
+			i = j
+		}
+	}
+	return append(toks, token{kind: tokEOF, pos: pos}), nil
+}
+
+func (p *parser) peek() token {
+	return p.toks[p.i]
+}
+
+func (p *parser) next() token {
+	t := p.toks[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *parser) isKeyword(kw string) bool {
+	t := p.peek()
+	return t.kind == tokWord && t.text == kw
+}
+
+func (p *parser) isPunct(s string) bool {
+	t := p.peek()
+	return t.kind == tokPunct && t.text == s
+}
+
+func (p *parser) unexpected(want string) *ParseError {
+	t := p.peek()
+	switch t.kind {
+	case tokEOF:
+		return errorAt(t.pos, "expected %s, got end of input", want)
+	case tokString:
+		return errorAt(t.pos, "expected %s, got %q", want, t.text)
+	default:
+		return errorAt(t.pos, "expected %s, got %s", want, t.text)
+	}
+}
+
+func (p *parser) expectKeyword(kw string) error {
+	if !p.isKeyword(kw) {
+		return p.unexpected(kw)
+	}
+	p.next()
+	return nil
+}
+
+func (p *parser) expectPunct(s string) error {
+	if !p.isPunct(s) {
+		return p.unexpected("'" + s + "'")
+	}
+	p.next()
+	return nil
+}
+
+func (p *parser) parseNumber() (uint32, error) {
+	t := p.peek()
+	if t.kind != tokWord {
+		return 0, p.unexpected("number")
+	}
+	n, err := strconv.ParseUint(t.text, 10, 32)
+	if err != nil {
+		return 0, p.unexpected("number")
+	}
+	p.next()
+	return uint32(n), nil
+}
+
+// parsePositive parses number which must not be zero.
+func (p *parser) parsePositive(what string) (uint32, error) {
+	pos := p.peek().pos
+	n, err := p.parseNumber()
+	if err == nil && n == 0 {
+		return 0, errorAt(pos, "%s must be positive", what)
+	}
+	return n, err
+}
+
+// parseIdent parses key or value: quoted string or word which is not a keyword.
+func (p *parser) parseIdent(what string) (string, error) {
+	t := p.peek()
+	if t.kind == tokString || (t.kind == tokWord && !keywords[t.text]) {
+		p.next()
+		return t.text, nil
+	}
+	return "", p.unexpected(what)
+}
+
+func (p *parser) parseGroup() (g SFGroup, err error) {
+	start := p.i
+	for {
+		switch t := p.peek(); {
+		case p.isKeyword("SELECT"):
+			p.next()
+			var s Select
+			if s.Count, err = p.parsePositive("select count"); err != nil {
+				return
+			}
+			if s.Key, err = p.parseIdent("key"); err != nil {
+				return
+			}
+			g.Selectors = append(g.Selectors, s)
+			if p.isKeyword("FROM") {
+				p.next()
+				if g.Filters, err = p.parseFilters(g.Filters); err != nil {
+					return
+				}
+			}
+		case p.isKeyword("FILTER"):
+			p.next()
+			if g.Filters, err = p.parseFilters(g.Filters); err != nil {
+				return
+			}
+		case p.isKeyword("EXCLUDE"):
+			p.next()
+			if err = p.parseExclude(&g); err != nil {
+				return
+			}
+		case p.isKeyword("LIMIT"):
+			p.next()
+			var l Limit
+			if l.Max, err = p.parsePositive("limit"); err != nil {
+				return
+			}
+			if err = p.expectKeyword("PER"); err != nil {
+				return
+			}
+			if l.Key, err = p.parseIdent("key"); err != nil {
+				return
+			}
+			g.Limits = append(g.Limits, l)
+		case t.kind == tokEOF || p.isPunct(";"):
+			if p.i == start {
+				return g, p.unexpected("SELECT, FILTER, EXCLUDE or LIMIT")
+			}
+			if len(g.Selectors) == 0 {
+				return g, errorAt(p.toks[start].pos, "group without SELECT")
+			}
+			return g, nil
+		default:
+			return g, p.unexpected("SELECT, FILTER, EXCLUDE, LIMIT or ';'")
+		}
+	}
+}
+
+func (p *parser) parseExclude(g *SFGroup) error {
+	for {
+		t := p.peek()
+		if t.kind != tokWord && t.kind != tokString {
+			return p.unexpected("node number or bucket path")
+		}
+		if strings.HasPrefix(t.text, Separator) {
+			if err := (SFGroup{ExcludeBuckets: []string{t.text}}).Validate(); err != nil {
+				return errorAt(t.pos, "%v", err)
+			}
+			g.ExcludeBuckets = append(g.ExcludeBuckets, t.text)
+		} else if n, err := strconv.ParseUint(t.text, 10, 32); err == nil && t.kind == tokWord {
+			g.Exclude = append(g.Exclude, uint32(n))
+		} else {
+			return p.unexpected("node number or bucket path")
+		}
+		p.next()
+
+		if !p.isPunct(",") {
+			break
+		}
+		p.next()
+	}
+	return nil
+}
+
+// parseFilters parses filter expression and appends resulting filters to fs.
+func (p *parser) parseFilters(fs []Filter) ([]Filter, error) {
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if e.key != "" {
+		return append(fs, Filter{Key: e.key, F: e.sf}), nil
+	}
+
+	// conjunction of different keys is split into filters with the same key
+	var (
+		keys  []string
+		byKey = make(map[string][]*filterExpr)
+	)
+	for _, part := range e.parts {
+		if _, ok := byKey[part.key]; !ok {
+			keys = append(keys, part.key)
+		}
+		byKey[part.key] = append(byKey[part.key], part)
+	}
+	for _, k := range keys {
+		fs = append(fs, Filter{Key: k, F: andExpr(byKey[k]).sf})
+	}
+	return fs, nil
+}
+
+func (p *parser) parseOr() (*filterExpr, error) {
+	e, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	if !p.isKeyword("OR") {
+		return e, nil
+	}
+	if e.key == "" {
+		return nil, errorAt(e.pos, "OR can be applied only to filters with the same key")
+	}
+
+	args := []SimpleFilter{*e.sf}
+	for p.isKeyword("OR") {
+		p.next()
+		o, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		if o.key != e.key {
+			return nil, errorAt(o.pos, "OR can be applied only to filters with the same key")
+		}
+		args = append(args, *o.sf)
+	}
+	return &filterExpr{pos: e.pos, key: e.key, sf: &SimpleFilter{
+		Op:   Operation_OR,
+		Args: &SimpleFilter_FArgs{FArgs: &SimpleFilters{Filters: args}},
+	}}, nil
+}
+
+func (p *parser) parseAnd() (*filterExpr, error) {
+	e, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	if !p.isKeyword("AND") {
+		return e, nil
+	}
+
+	es := []*filterExpr{e}
+	for p.isKeyword("AND") {
+		p.next()
+		if e, err = p.parseUnary(); err != nil {
+			return nil, err
+		}
+		es = append(es, e)
+	}
+	return andExpr(es), nil
+}
+
+// andExpr returns conjunction of es. If all parts have the same key,
+// they are combined into single AND filter.
+func andExpr(es []*filterExpr) *filterExpr {
+	if len(es) == 1 {
+		return es[0]
+	}
+
+	var (
+		r    = &filterExpr{pos: es[0].pos, key: es[0].key}
+		args []SimpleFilter
+	)
+	for _, e := range es {
+		if e.key == "" {
+			r.parts = append(r.parts, e.parts...)
+		} else {
+			r.parts = append(r.parts, e)
+		}
+	}
+	for _, e := range r.parts {
+		if e.key != r.key {
+			r.key = ""
+			return r
+		}
+		args = append(args, *e.sf)
+	}
+	r.sf = &SimpleFilter{
+		Op:   Operation_AND,
+		Args: &SimpleFilter_FArgs{FArgs: &SimpleFilters{Filters: args}},
+	}
+	return r
+}
+
+func (p *parser) parseUnary() (*filterExpr, error) {
+	start := p.peek().pos
+
+	if p.isKeyword("NOT") {
+		p.next()
+		e, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if e.key == "" {
+			return nil, errorAt(e.pos, "NOT can be applied only to filters with the same key")
+		}
+		return &filterExpr{pos: start, key: e.key, sf: FilterNOT(e.sf)}, nil
+	}
+
+	if p.isPunct("(") {
+		p.next()
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expectPunct(")"); err != nil {
+			return nil, err
+		}
+		e.pos = start
+		return e, nil
+	}
+
+	key, err := p.parseIdent("filter key, NOT or '('")
+	if err != nil {
+		return nil, err
+	}
+
+	not := p.isKeyword("NOT")
+	if not {
+		p.next()
+	}
+
+	e, err := p.parseCondition(start, key)
+	if err != nil {
+		return nil, err
+	}
+	if not {
+		e.sf = FilterNOT(e.sf)
+	}
+	return e, nil
+}
+
+// parseCondition parses the rest of <key> <op> <value> [AS <type>]
+// or <key> IN (<value>, ...) expression.
+func (p *parser) parseCondition(start position, key string) (*filterExpr, error) {
+	if p.isKeyword("IN") {
+		p.next()
+		values, err := p.parseValues()
+		if err != nil {
+			return nil, err
+		}
+		return &filterExpr{pos: start, key: key, sf: FilterIn(values...)}, nil
+	}
+
+	opTok := p.peek()
+	op, ok := Operation_value[opTok.text]
+	if opTok.kind != tokWord || !ok || isComposite(Operation(op)) {
+		return nil, p.unexpected("operation or IN")
+	}
+	p.next()
+
+	value, err := p.parseIdent("value")
+	if err != nil {
+		return nil, err
+	}
+
+	var typ int32
+	if p.isKeyword("AS") {
+		p.next()
+		t := p.peek()
+		if typ, ok = Type_value[t.text]; t.kind != tokWord || !ok {
+			return nil, p.unexpected("type")
+		}
+		p.next()
+	}
+
+	f := Filter{Key: key, F: NewTypedFilter(Operation(op), Type(typ), value)}
+	if err := f.Validate(); err != nil {
+		return nil, errorAt(start, "%v", err)
+	}
+	return &filterExpr{pos: start, key: key, sf: f.F}, nil
+}
+
+func (p *parser) parseValues() ([]string, error) {
+	if err := p.expectPunct("("); err != nil {
+		return nil, err
+	}
+
+	var values []string
+	for {
+		v, err := p.parseIdent("value")
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+
+		if p.isPunct(")") {
+			p.next()
+			return values, nil
+		}
+		if !p.isPunct(",") {
+			return nil, p.unexpected("',' or ')'")
+		}
+		p.next()
+	}
+}
+
+// isComposite checks if op combines nested filters or has no operand.
+func isComposite(op Operation) bool {
+	return op == Operation_NP || op == Operation_OR || op == Operation_AND || op == Operation_NOT
+}
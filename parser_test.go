@@ -0,0 +1,119 @@
+package netmap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRule(t *testing.T) {
+	r, err := ParseRule(`REP 3 SELECT 2 Country FROM Location EQ Europe SELECT 1 City FILTER Trust GE 10`)
+	require.NoError(t, err)
+	require.Equal(t, PlacementRule{
+		ReplFactor: 3,
+		SFGroups: []SFGroup{{
+			Selectors: []Select{{Count: 2, Key: "Country"}, {Count: 1, Key: "City"}},
+			Filters: []Filter{
+				{Key: "Location", F: FilterEQ("Europe")},
+				{Key: "Trust", F: NewFilter(Operation_GE, "10")},
+			},
+		}},
+	}, r)
+
+	r, err = ParseRule(`
+		# European replicas
+		REP 4
+		SELECT 2 Country
+		FILTER Location EQ Europe AND Country NOT IN (Russia, "Great Britain")
+		FILTER Trust GE 10 AND Trust LE 20 AND City NOT LIKE San*
+		EXCLUDE 7, 8, /Location:Europe/Country:France
+		LIMIT 1 PER City;
+
+		SELECT 1 Node
+		FILTER NOT (Country EQ China OR Country EQ Japan)
+		FILTER Node.C GE 1TB AS Size;
+	`)
+	require.NoError(t, err)
+	require.Equal(t, PlacementRule{
+		ReplFactor: 4,
+		SFGroups: []SFGroup{
+			{
+				Selectors: []Select{{Count: 2, Key: "Country"}},
+				Filters: []Filter{
+					{Key: "Location", F: FilterEQ("Europe")},
+					{Key: "Country", F: FilterNOT(FilterIn("Russia", "Great Britain"))},
+					{Key: "Trust", F: FilterAND(NewFilter(Operation_GE, "10"), NewFilter(Operation_LE, "20"))},
+					{Key: "City", F: FilterNOT(FilterLike("San*"))},
+				},
+				Exclude:        []uint32{7, 8},
+				ExcludeBuckets: []string{"/Location:Europe/Country:France"},
+				Limits:         []Limit{{Key: "City", Max: 1}},
+			},
+			{
+				Selectors: []Select{{Count: 1, Key: NodesBucket}},
+				Filters: []Filter{
+					{Key: "Country", F: FilterNOT(FilterOR(FilterEQ("China"), FilterEQ("Japan")))},
+					{Key: CapacityFilterKey, F: NewTypedFilter(Operation_GE, Type_Size, "1TB")},
+				},
+			},
+		},
+	}, r)
+
+	t.Run("nested expressions", func(t *testing.T) {
+		r, err := ParseRule(`SELECT 1 City FILTER (City EQ Berlin OR City REGEX "^San (Jose|Diego)$") AND NOT City PREFIX "New "`)
+		require.NoError(t, err)
+		require.Equal(t, []Filter{{Key: "City", F: FilterAND(
+			FilterOR(FilterEQ("Berlin"), FilterRegex("^San (Jose|Diego)$")),
+			FilterNOT(FilterPrefix("New ")),
+		)}}, r.SFGroups[0].Filters)
+
+		r, err = ParseRule(`FILTER "SELECT" EQ "REP" AND Version GE v1.2.0 AS Version SELECT 1 Node`)
+		require.NoError(t, err)
+		require.Equal(t, PlacementRule{SFGroups: []SFGroup{{
+			Filters: []Filter{
+				{Key: "SELECT", F: FilterEQ("REP")},
+				{Key: "Version", F: NewTypedFilter(Operation_GE, Type_Version, "v1.2.0")},
+			},
+			Selectors: []Select{{Count: 1, Key: NodesBucket}},
+		}}}, r)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		cases := []struct {
+			text string
+			err  string
+		}{
+			{``, "line 1, column 1: expected SELECT, FILTER, EXCLUDE or LIMIT, got end of input"},
+			{`REP`, "line 1, column 4: expected number, got end of input"},
+			{`REP three SELECT 1 City`, "line 1, column 5: expected number, got three"},
+			{`REP 3 SELECT 1`, "line 1, column 15: expected key, got end of input"},
+			{"REP 3\nSELECT 1 City\nFILTER Trust GT ten", "line 3, column 8: filter Trust GT ten: can't parse \"ten\" as integer"},
+			{"REP 3\n  SELECT 1 City WHERE", "line 2, column 17: expected SELECT, FILTER, EXCLUDE, LIMIT or ';', got WHERE"},
+			{`SELECT 1 City FILTER City EQ`, "line 1, column 29: expected value, got end of input"},
+			{`SELECT 1 City FILTER City IS Berlin`, "line 1, column 27: expected operation or IN, got IS"},
+			{`SELECT 1 City FILTER City AND Berlin`, "line 1, column 27: expected operation or IN, got AND"},
+			{`SELECT 1 City FILTER City EQ Berlin OR Country EQ Germany`, "line 1, column 40: OR can be applied only to filters with the same key"},
+			{`SELECT 1 City FILTER NOT (City EQ Berlin AND Country EQ Germany)`, "line 1, column 26: NOT can be applied only to filters with the same key"},
+			{`SELECT 1 City FILTER City IN (Berlin, Bonn`, "line 1, column 43: expected ',' or ')', got end of input"},
+			{`SELECT 1 City FILTER City NOT Berlin`, "line 1, column 31: expected operation or IN, got Berlin"},
+			{`SELECT 1 City FILTER City LIKE "[" `, "line 1, column 22: filter City LIKE [: invalid pattern \"[\""},
+			{`SELECT 1 City FILTER Size GT 1 AS Weight`, "line 1, column 35: expected type, got Weight"},
+			{`SELECT 1 City EXCLUDE Berlin`, "line 1, column 23: expected node number or bucket path, got Berlin"},
+			{`SELECT 1 City EXCLUDE /City`, "line 1, column 23: exclude \"/City\": bucket \"City\": wrong format"},
+			{`SELECT 1 City LIMIT 1 City`, "line 1, column 23: expected PER, got City"},
+			{`SELECT 1 City;;`, "line 1, column 15: expected SELECT, FILTER, EXCLUDE or LIMIT, got ;"},
+			{`SELECT 1 "City`, "line 1, column 10: unterminated string"},
+			{`REP 0 SELECT 1 City`, "line 1, column 5: replication factor must be positive"},
+			{`REP 1 SELECT 0 City`, "line 1, column 14: select count must be positive"},
+			{`SELECT 1 City LIMIT 0 PER City`, "line 1, column 21: limit must be positive"},
+			{`REP 1 FILTER City EQ Berlin`, "line 1, column 7: group without SELECT"},
+			{"SELECT 1 City;\n  EXCLUDE 7", "line 2, column 3: group without SELECT"},
+		}
+
+		for _, c := range cases {
+			_, err := ParseRule(c.text)
+			require.EqualError(t, err, c.err, c.text)
+			require.IsType(t, (*ParseError)(nil), err)
+		}
+	})
+}
@@ -0,0 +1,251 @@
+package netmap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// Patch represents set of operations which transform one netmap into another.
+// Only N of the nodes in Move is used.
+type Patch struct {
+	Add    []NodeOptions
+	Remove []uint32
+	Move   []NodeOptions
+	Update Nodes
+}
+
+// Patch returns patch which transforms old netmap of d into new one.
+func (d BucketDiff) Patch() (p Patch) {
+	p.Add = d.Added
+	for i := range d.Removed {
+		p.Remove = append(p.Remove, d.Removed[i].N)
+	}
+	for i := range d.Moved {
+		p.Move = append(p.Move, NodeOptions{
+			Node:    Node{N: d.Moved[i].N},
+			Options: d.Moved[i].To,
+		})
+	}
+	for i := range d.Updated {
+		p.Update = append(p.Update, d.Updated[i].To)
+	}
+	return
+}
+
+// Apply applies all operations from p to b in the following order:
+// remove, update, move, add.
+// Options of added and moved nodes must not conflict, as in UpdateNode.
+// Bucket is not modified if error is returned.
+func (b *Bucket) Apply(p Patch) error {
+	var c = b.Copy()
+
+	for _, n := range p.Remove {
+		if err := c.RemoveNode(n); err != nil {
+			return err
+		}
+	}
+
+	for _, n := range p.Update {
+		if search(c.nodes, n.N) == len(c.nodes) {
+			return errors.Errorf("node %d not found", n.N)
+		}
+		c.setNode(n)
+	}
+
+	for _, m := range p.Move {
+		i := search(c.nodes, m.Node.N)
+		if i == len(c.nodes) {
+			return errors.Errorf("node %d not found", m.Node.N)
+		}
+
+		node := c.nodes[i]
+		if _, _, err := optionsBucket(node, m.Options); err != nil {
+			return err
+		}
+		c.dropOptions(node.N, func(string) bool { return true })
+		if err := c.addNode(node, m.Options...); err != nil {
+			return err
+		}
+	}
+
+	for _, a := range p.Add {
+		if search(c.nodes, a.Node.N) != len(c.nodes) {
+			return errors.Errorf("node %d already exists", a.Node.N)
+		}
+		if _, _, err := optionsBucket(a.Node, a.Options); err != nil {
+			return err
+		}
+		if err := c.addNode(a.Node, a.Options...); err != nil {
+			return err
+		}
+	}
+
+	*b = c
+	return nil
+}
+
+// setNode replaces node with the same index as n in b and all of its subbuckets.
+func (b *Bucket) setNode(n Node) {
+	i := search(b.nodes, n.N)
+	if i == len(b.nodes) {
+		return
+	}
+
+	// nodes can be shared between buckets, so new slice must be allocated
+	nodes := make(Nodes, len(b.nodes))
+	copy(nodes, b.nodes)
+	nodes[i] = n
+	b.nodes = nodes
+
+	for i := range b.children {
+		b.children[i].setNode(n)
+	}
+}
+
+// Write writes Patch with this byte structure
+// [Add][Remove][Move][Update]
+// where Add and Move are
+// [lnNodes][Node1]...[NodeN][lnMetrics][Metrics1]...[MetricsN][lnOpts1][Opts1]...[lnOptsN][OptsN],
+// Remove is
+// [lnNodes][N1]...[NN]
+// and Update is
+// [lnNodes][Node1]...[NodeN][lnMetrics][Metrics1]...[MetricsN].
+func (p Patch) Write(w io.Writer) error {
+	var err error
+
+	if err = writeNodeOptions(w, p.Add); err != nil {
+		return err
+	}
+
+	if err = binary.Write(w, binary.BigEndian, int32(len(p.Remove))); err != nil {
+		return err
+	}
+	if err = binary.Write(w, binary.BigEndian, p.Remove); err != nil {
+		return err
+	}
+
+	if err = writeNodeOptions(w, p.Move); err != nil {
+		return err
+	}
+
+	if err = p.Update.Write(w); err != nil {
+		return err
+	}
+	return p.Update.writeMetrics(w)
+}
+
+// Read reads Patch in serialized form:
+// [Add][Remove][Move][Update]
+func (p *Patch) Read(r io.Reader) error {
+	var (
+		err error
+		ln  int32
+	)
+
+	if p.Add, err = readNodeOptions(r); err != nil {
+		return err
+	}
+
+	if err = binary.Read(r, binary.BigEndian, &ln); err != nil {
+		return err
+	}
+	// ln is not trusted, so Remove is not preallocated
+	for ; ln > 0; ln-- {
+		var n uint32
+		if err = binary.Read(r, binary.BigEndian, &n); err != nil {
+			return err
+		}
+		p.Remove = append(p.Remove, n)
+	}
+
+	if p.Move, err = readNodeOptions(r); err != nil {
+		return err
+	}
+
+	if err = p.Update.Read(r); err != nil {
+		return err
+	}
+	return p.Update.readMetrics(r)
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface.
+func (p Patch) MarshalBinary() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := p.Write(buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface.
+func (p *Patch) UnmarshalBinary(data []byte) error {
+	return p.Read(bytes.NewReader(data))
+}
+
+func writeNodeOptions(w io.Writer, ns []NodeOptions) error {
+	var (
+		err   error
+		nodes = make(Nodes, 0, len(ns))
+	)
+
+	for i := range ns {
+		nodes = append(nodes, ns[i].Node)
+	}
+	if err = nodes.Write(w); err != nil {
+		return err
+	}
+	if err = nodes.writeMetrics(w); err != nil {
+		return err
+	}
+
+	for i := range ns {
+		if err = binary.Write(w, binary.BigEndian, int32(len(ns[i].Options))); err != nil {
+			return err
+		}
+		for _, o := range ns[i].Options {
+			if err = binary.Write(w, binary.BigEndian, int32(len(o))); err != nil {
+				return err
+			}
+			if err = binary.Write(w, binary.BigEndian, []byte(o)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func readNodeOptions(r io.Reader) ([]NodeOptions, error) {
+	var (
+		err   error
+		nodes Nodes
+		ns    []NodeOptions
+	)
+
+	if err = nodes.Read(r); err != nil {
+		return nil, err
+	}
+	if err = nodes.readMetrics(r); err != nil {
+		return nil, err
+	}
+
+	for i := range nodes {
+		var ln int32
+		if err = binary.Read(r, binary.BigEndian, &ln); err != nil {
+			return nil, err
+		}
+
+		var opts []string
+		for ; ln > 0; ln-- {
+			opt, err := readString(r)
+			if err != nil {
+				return nil, err
+			}
+			opts = append(opts, opt)
+		}
+		ns = append(ns, NodeOptions{Node: nodes[i], Options: opts})
+	}
+	return ns, nil
+}
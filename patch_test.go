@@ -0,0 +1,95 @@
+package netmap
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBucket_Apply(t *testing.T) {
+	var (
+		o, n Bucket
+		p    Patch
+		err  error
+	)
+
+	o, err = newStrawRoot(
+		strawBucket{"/Location:Europe/Country:Germany", Nodes{{N: 1, C: 1}, {N: 2, C: 2}}},
+		strawBucket{"/Location:Europe/Country:Austria", Nodes{{N: 3, C: 3}}},
+		strawBucket{"/Location:Asia/Country:Japan", Nodes{{N: 4, C: 4}}},
+		strawBucket{"/Trust:10", Nodes{{N: 2, C: 2}, {N: 4, C: 4}}},
+	)
+	require.NoError(t, err)
+
+	n, err = newStrawRoot(
+		strawBucket{"/Location:Europe/Country:Germany", Nodes{{N: 1, C: 1}}},
+		strawBucket{"/Location:Europe/Country:Austria", Nodes{{N: 2, C: 2}, {N: 3, C: 30, P: 1, M: NewMetrics(map[string]float64{"uptime": 1})}}},
+		strawBucket{"/Location:Asia/Country:Korea", Nodes{{N: 5, C: 5, M: NewMetrics(map[string]float64{"uptime": 0.5})}}},
+		strawBucket{"/Trust:10", Nodes{{N: 5, C: 5, M: NewMetrics(map[string]float64{"uptime": 0.5})}}},
+	)
+	require.NoError(t, err)
+
+	p = Diff(o, n).Patch()
+	require.Equal(t, []uint32{4}, p.Remove)
+	require.Len(t, p.Add, 1)
+	require.Len(t, p.Move, 1)
+	require.Len(t, p.Update, 1)
+
+	t.Run("apply", func(t *testing.T) {
+		c := o.Copy()
+		require.NoError(t, c.Apply(p))
+		require.True(t, Diff(c, n).Empty())
+		require.True(t, Diff(o, o.Copy()).Empty(), "source bucket must not change")
+	})
+
+	t.Run("marshal", func(t *testing.T) {
+		var r Patch
+
+		data, err := p.MarshalBinary()
+		require.NoError(t, err)
+		require.NoError(t, r.UnmarshalBinary(data))
+		require.Equal(t, p, r)
+
+		require.Error(t, r.UnmarshalBinary(data[:len(data)-1]))
+
+		t.Run("corrupted", func(t *testing.T) {
+			var r Patch
+
+			data, err := Patch{Add: []NodeOptions{{Node: Node{N: 1}, Options: []string{"/a:bcd"}}}}.MarshalBinary()
+			require.NoError(t, err)
+
+			// option length follows nodes, metrics and number of options
+			binary.BigEndian.PutUint32(data[32:], 0xFFFFFFFF)
+			require.Error(t, r.UnmarshalBinary(data))
+
+			// Remove length is not trusted too
+			data, err = Patch{Remove: []uint32{1}}.MarshalBinary()
+			require.NoError(t, err)
+			binary.BigEndian.PutUint32(data[8:], 0x7FFFFFFF)
+			require.Error(t, r.UnmarshalBinary(data))
+		})
+
+		data, err = Patch{}.MarshalBinary()
+		require.NoError(t, err)
+		r = Patch{}
+		require.NoError(t, r.UnmarshalBinary(data))
+		require.Equal(t, Patch{}, r)
+	})
+
+	t.Run("invalid patch", func(t *testing.T) {
+		c := o.Copy()
+		for _, p := range []Patch{
+			{Remove: []uint32{42}},
+			{Update: Nodes{{N: 42}}},
+			{Move: []NodeOptions{{Node: Node{N: 42}, Options: []string{"/Location:Asia"}}}},
+			{Add: []NodeOptions{{Node: Node{N: 1}, Options: []string{"/Location:Asia"}}}},
+			{Remove: []uint32{1}, Add: []NodeOptions{{Node: Node{N: 6}, Options: []string{"Location:Asia"}}}},
+			{Move: []NodeOptions{{Node: Node{N: 1}, Options: []string{"/Location:Europe", "/Location:Asia"}}}},
+			{Add: []NodeOptions{{Node: Node{N: 42}, Options: []string{"/Location:Asia/Country:Korea", "/Location:Asia/Country:Japan"}}}},
+		} {
+			require.Error(t, c.Apply(p))
+			require.Equal(t, o, c)
+		}
+	})
+}
@@ -0,0 +1,36 @@
+package netmap
+
+import (
+	"github.com/pkg/errors"
+)
+
+// Place returns exactly rule.ReplFactor primary nodes for pivot.
+// Every group of rule must be satisfied. Nodes are picked round-robin across
+// the groups and, inside every group, across the buckets it selected,
+// so replicas are spread as the selectors require. Buckets and nodes are taken
+// in HRW order, so the result is deterministic for the same netmap and the
+// first node is the one FindNodesOrdered returns first for the first group.
+// Error is returned if groups can't supply enough distinct nodes.
+func (b *Bucket) Place(rule PlacementRule, pivot []byte) (Nodes, error) {
+	if rule.ReplFactor == 0 {
+		return nil, errors.New("replication factor must be positive")
+	}
+
+	lists := make([]Nodes, 0, len(rule.SFGroups))
+	for i, s := range rule.SFGroups {
+		_, r := b.findRanked(pivot, s)
+		if r == nil {
+			if err := b.Explain(s); err != nil {
+				return nil, errors.Wrapf(err, "group %d can't be satisfied", i)
+			}
+			return nil, errors.Errorf("group %d can't be satisfied", i)
+		}
+		lists = append(lists, r.interleave())
+	}
+
+	nodes := unique(roundRobin(lists))
+	if ln := uint32(len(nodes)); ln < rule.ReplFactor {
+		return nil, errors.Errorf("not enough nodes: need %d, found %d", rule.ReplFactor, ln)
+	}
+	return nodes[:rule.ReplFactor], nil
+}
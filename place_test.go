@@ -0,0 +1,84 @@
+package netmap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBucket_Place(t *testing.T) {
+	root, err := newRoot(
+		bucket{"/Location:Europe/Country:Germany", []uint32{1, 2, 3}},
+		bucket{"/Location:Europe/Country:Austria", []uint32{4, 5}},
+		bucket{"/Location:Asia/Country:Korea", []uint32{6, 7}},
+	)
+	require.NoError(t, err)
+
+	rule := PlacementRule{
+		ReplFactor: 3,
+		SFGroups: []SFGroup{
+			{Selectors: []Select{{Key: "Country", Count: 2}, {Key: NodesBucket, Count: 2}}},
+			{
+				Filters:   []Filter{{Key: "Location", F: FilterEQ("Asia")}},
+				Selectors: []Select{{Key: NodesBucket, Count: 2}},
+			},
+		},
+	}
+
+	for _, pivot := range SamplePivots(20) {
+		nodes, err := root.Place(rule, pivot)
+		require.NoError(t, err)
+		require.Len(t, nodes, 3)
+		require.Equal(t, root.FindNodesOrdered(pivot, rule.SFGroups[0])[0], nodes[0])
+		require.Subset(t, root.FindNodes(pivot, rule.SFGroups...), nodes)
+
+		again, err := root.Place(rule, pivot)
+		require.NoError(t, err)
+		require.Equal(t, nodes, again)
+	}
+
+	t.Run("spread", func(t *testing.T) {
+		rule := PlacementRule{
+			ReplFactor: 2,
+			SFGroups: []SFGroup{
+				{Selectors: []Select{{Key: "Country", Count: 2}, {Key: NodesBucket, Count: 2}}},
+			},
+		}
+		for _, pivot := range SamplePivots(20) {
+			nodes, err := root.Place(rule, pivot)
+			require.NoError(t, err)
+			require.Len(t, nodes, 2)
+			require.NotEqual(t, root.GetOptionsByNode(nodes[0].N), root.GetOptionsByNode(nodes[1].N))
+		}
+	})
+
+	t.Run("unsatisfied group", func(t *testing.T) {
+		rule := PlacementRule{
+			ReplFactor: 2,
+			SFGroups: []SFGroup{
+				{Selectors: []Select{{Key: NodesBucket, Count: 2}}},
+				{
+					Filters:   []Filter{{Key: "Location", F: FilterEQ("Asia")}},
+					Selectors: []Select{{Key: NodesBucket, Count: 3}},
+				},
+			},
+		}
+		_, err := root.Place(rule, []byte("pivot"))
+		require.EqualError(t, err, "group 1 can't be satisfied: selection failed: "+
+			"filter Location EQ Asia left 2 nodes; "+
+			"select 3 Node needed 3 nodes under / but found 2")
+	})
+
+	t.Run("not enough nodes", func(t *testing.T) {
+		rule.ReplFactor = 7
+		rule.SFGroups[0].Selectors[0].Count = 3
+		_, err := root.Place(rule, []byte("pivot"))
+		require.EqualError(t, err, "not enough nodes: need 7, found 6")
+
+	})
+
+	t.Run("zero replication factor", func(t *testing.T) {
+		_, err := root.Place(PlacementRule{SFGroups: rule.SFGroups}, nil)
+		require.Error(t, err)
+	})
+}
@@ -3,6 +3,7 @@ package netmap
 import (
 	"bytes"
 	"encoding/binary"
+	"hash/crc32"
 	"io"
 	"sort"
 	"strings"
@@ -17,6 +18,17 @@ const (
 
 	// NodesBucket is the name for optionless bucket containing only nodes.
 	NodesBucket = "Node"
+
+	// NodeFilterPrefix is the prefix of filter keys matching node's own values
+	// instead of bucket values. The rest of the key is "C" for capacity,
+	// "P" for price or the name of metric.
+	NodeFilterPrefix = NodesBucket + "."
+
+	// CapacityFilterKey is the filter key matching node capacity.
+	CapacityFilterKey = NodeFilterPrefix + "C"
+
+	// PriceFilterKey is the filter key matching node price.
+	PriceFilterKey = NodeFilterPrefix + "P"
 )
 
 type (
@@ -36,11 +48,13 @@ type (
 		children []Bucket
 	}
 
-	// Node type represents single graph leaf with index N, capacity C and price P.
+	// Node type represents single graph leaf with index N, capacity C, price P
+	// and arbitrary named numeric metrics M.
 	Node struct {
 		N uint32
 		C uint64
 		P uint64
+		M Metrics
 	}
 
 	// Nodes represents slice of graph leafs.
@@ -56,6 +70,27 @@ type (
 func (n Node) Hash() uint64 {
 	return uint64(n.N)
 }
+
+// Metric returns value of n's metric name or 0 if it is missing.
+func (n Node) Metric(name string) float64 {
+	v, _ := n.M.Get(name)
+	return v
+}
+
+// value returns capacity, price or metric of n depending on name.
+func (n Node) value(name string) float64 {
+	switch name {
+	case "C":
+		return float64(n.C)
+	case "P":
+		return float64(n.P)
+	default:
+		return n.Metric(name)
+	}
+}
+
+// Write writes only N, C and P of n. Metrics are written separately
+// by Bucket.Write to avoid duplicating them on every level.
 func (n Node) Write(w io.Writer) error {
 	var err error
 	if err = binary.Write(w, binary.BigEndian, n.N); err != nil {
@@ -106,13 +141,15 @@ func (n *Nodes) Read(r io.Reader) error {
 	if err = binary.Read(r, binary.BigEndian, &ln); err != nil {
 		return err
 	}
-	if ln > 0 {
-		nodes := make(Nodes, ln)
-		for i := range nodes {
-			if err = nodes[i].Read(r); err != nil {
-				return err
-			}
+	var nodes Nodes
+	for ; ln > 0; ln-- {
+		var node Node
+		if err = node.Read(r); err != nil {
+			return err
 		}
+		nodes = append(nodes, node)
+	}
+	if nodes != nil {
 		*n = nodes
 	}
 	return nil
@@ -143,6 +180,7 @@ func (b Bucket) Hash() uint64 {
 }
 
 // FindGraph returns random subgraph, corresponding to specified placement rule.
+// Use Explain to find out why nil is returned.
 func (b *Bucket) FindGraph(pivot []byte, ss ...SFGroup) (c *Bucket) {
 	var g *Bucket
 
@@ -157,13 +195,13 @@ func (b *Bucket) FindGraph(pivot []byte, ss ...SFGroup) (c *Bucket) {
 }
 
 func (b *Bucket) findGraph(pivot []byte, s SFGroup) (c *Bucket) {
-	if c = b.GetMaxSelection(s); c != nil {
-		return c.GetSelection(s.Selectors, pivot)
-	}
+	c, _ = b.findRanked(pivot, s)
 	return
 }
 
 // FindNodes returns list of nodes, corresponding to specified placement rule.
+// Nodes are sorted by N, use FindNodesOrdered to get them in HRW order.
+// Use Explain to find out why some of the groups were not satisfied.
 func (b *Bucket) FindNodes(pivot []byte, ss ...SFGroup) (nodes Nodes) {
 	for _, s := range ss {
 		nodes = merge(nodes, b.findNodes(pivot, s))
@@ -171,17 +209,64 @@ func (b *Bucket) FindNodes(pivot []byte, ss ...SFGroup) (nodes Nodes) {
 	return
 }
 
-func (b *Bucket) findNodes(pivot []byte, s SFGroup) Nodes {
-	var c *Bucket
+// FindNodesOrdered returns the same nodes as FindNodes, but in the order
+// in which GetSelection ranked them for pivot: nodes of the higher-ranked bucket
+// go first and nodes inside every bucket follow in weighted HRW order.
+// So the first node is the highest-scoring one and can be used as primary.
+// Results of the groups follow in order of ss, every node is returned once.
+func (b *Bucket) FindNodesOrdered(pivot []byte, ss ...SFGroup) Nodes {
+	var nodes Nodes
+	for _, s := range ss {
+		if _, r := b.findRanked(pivot, s); r != nil {
+			nodes = append(nodes, r.flatten()...)
+		}
+	}
+	return unique(nodes)
+}
 
-	if c = b.GetMaxSelection(s); c != nil {
-		if c = c.GetSelection(s.Selectors, pivot); c != nil {
-			return c.Nodelist()
+// orderNodes returns copy of nodes sorted by weighted HRW for pivot.
+// If pivot is empty, nodes are returned as is.
+func orderNodes(nodes Nodes, pivot []byte) Nodes {
+	if len(nodes) == 0 || len(pivot) == 0 {
+		return nodes
+	}
+
+	// nodes can be shared between buckets, so new slice must be allocated
+	nodes = append(Nodes(nil), nodes...)
+	hrw.SortSliceByWeightValue(nodes, nodes.Weights(), hrw.Hash(pivot))
+	return nodes
+}
+
+// unique returns nodes without duplicates keeping the first occurrence.
+func unique(nodes Nodes) Nodes {
+	var (
+		res  Nodes
+		seen = make(map[uint32]bool, len(nodes))
+	)
+	for i := range nodes {
+		if !seen[nodes[i].N] {
+			seen[nodes[i].N] = true
+			res = append(res, nodes[i])
 		}
 	}
+	return res
+}
+
+func (b *Bucket) findNodes(pivot []byte, s SFGroup) Nodes {
+	if c, _ := b.findRanked(pivot, s); c != nil {
+		return c.Nodelist()
+	}
 	return nil
 }
 
+// findRanked returns subgraph selected by s together with its ranking.
+func (b *Bucket) findRanked(pivot []byte, s SFGroup) (*Bucket, *ranking) {
+	if c := b.GetMaxSelection(s); c != nil {
+		return c.getSelection(s.Selectors, pivot, c.newLimiter(s.Limits), "", nil)
+	}
+	return nil, nil
+}
+
 // Copy returns deep copy of Bucket.
 func (b Bucket) Copy() (bc Bucket) {
 	bc.weight = b.weight
@@ -205,6 +290,7 @@ func (b Bucket) Copy() (bc Bucket) {
 // IsValid checks if bucket is well-formed:
 // - all nodes contained in sub-bucket must belong to this;
 // - there must be no nodes belonging to 2 buckets.
+// Validate performs stricter checks and describes found problems.
 func (b Bucket) IsValid() bool {
 	var (
 		ns    Nodes
@@ -228,12 +314,37 @@ func (b Bucket) IsValid() bool {
 }
 
 func (b Bucket) findAllowed(fs []Filter) (nodes Nodes) {
+	nodes, _ = b.findAllowedC(fs, false)
+	return
+}
+
+// findAllowedC returns nodes satisfying all filters fs.
+// If strict is set, bucket values are checked with Match and
+// the first error is returned.
+func (b Bucket) findAllowedC(fs []Filter, strict bool) (nodes Nodes, err error) {
 	nodes = b.nodes
 
 	for i := range fs {
 		var allowed Nodes
+		if strings.HasPrefix(fs[i].Key, NodeFilterPrefix) {
+			name := fs[i].Key[len(NodeFilterPrefix):]
+			for _, n := range b.nodes {
+				if fs[i].F.checkFloat(n.value(name)) {
+					allowed = append(allowed, n)
+				}
+			}
+			nodes = intersect(nodes, allowed)
+			continue
+		}
+
 		for _, c := range b.findKey(fs[i].Key) {
-			if fs[i].F.Check(c.Value) {
+			ok := true
+			if !strict {
+				ok = fs[i].F.Check(c.Value)
+			} else if ok, err = fs[i].F.Match(c.Value); err != nil {
+				return nil, errors.Wrapf(err, "filter %s on bucket %s", formatFilter(fs[i]), c.Name())
+			}
+			if ok {
 				allowed = append(allowed, c.nodes...)
 			}
 		}
@@ -294,28 +405,60 @@ func (b Bucket) filterSubtree(filter FilterFunc) *Bucket {
 	return nil
 }
 
-func (b Bucket) getMaxSelection(ss []Select, filter FilterFunc) (*Bucket, uint32) {
-	return b.getMaxSelectionC(ss, filter, true)
+func (b Bucket) getMaxSelection(ss []Select, filter FilterFunc, l *limiter) (*Bucket, uint32) {
+	return b.getMaxSelectionC(ss, filter, l, true, "", nil)
 }
 
-func (b Bucket) getMaxSelectionC(ss []Select, filter FilterFunc, cut bool) (*Bucket, uint32) {
+// getMaxSelectionC finds maximal selection in b. If l is not nil, only
+// the nodes which can be selected together without exceeding limits are counted.
+// If rs is not nil, reasons of failed selects are appended to it.
+func (b Bucket) getMaxSelectionC(ss []Select, filter FilterFunc, l *limiter, cut bool, path string, rs *[]Reason) (*Bucket, uint32) {
 	var (
 		root     Bucket
 		r        *Bucket
 		sel      []Select
 		count, n uint32
 		cutc     bool
+		cpath    string
+		index    int
 	)
 
 	if len(ss) == 0 || ss[0].Key == NodesBucket {
 		if r = b.filterSubtree(filter); r != nil {
-			if count = uint32(len(r.nodes)); len(ss) == 0 || ss[0].Count <= count {
-				return r, count
+			count = uint32(len(r.nodes))
+		}
+		if r != nil && l != nil {
+			var allowed uint32
+			if len(ss) != 0 {
+				allowed = l.capacity(r.nodes)
+			} else if l.add(r.nodes...) {
+				// the whole bucket is selected, so all its nodes must fit
+				l.remove(r.nodes...)
+				allowed = count
+			}
+			if allowed < count {
+				if rs != nil {
+					*rs = append(*rs, Reason{Kind: LimitReason, Path: path, Count: allowed})
+				}
+				count = allowed
+				if len(ss) == 0 {
+					r = nil
+				}
 			}
 		}
+		if r != nil && (len(ss) == 0 || ss[0].Count <= count) {
+			return r, count
+		}
+		if rs != nil && len(ss) != 0 {
+			*rs = append(*rs, Reason{Kind: SelectReason, Path: path, Select: ss[0], Count: count})
+		}
 		return nil, 0
 	}
 
+	if rs != nil {
+		index = len(*rs)
+	}
+
 	root.Key = b.Key
 	root.Value = b.Value
 	for _, c := range b.children {
@@ -323,7 +466,10 @@ func (b Bucket) getMaxSelectionC(ss []Select, filter FilterFunc, cut bool) (*Buc
 		if cutc = c.Key == ss[0].Key; cutc {
 			sel = ss[1:]
 		}
-		if r, n = c.getMaxSelectionC(sel, filter, cutc); r != nil {
+		if rs != nil {
+			cpath = path + Separator + c.Name()
+		}
+		if r, n = c.getMaxSelectionC(sel, filter, l, cutc, cpath, rs); r != nil {
 			root.children = append(root.children, *r)
 			root.nodes = append(root.nodes, r.Nodelist()...)
 			if cutc {
@@ -339,54 +485,199 @@ func (b Bucket) getMaxSelectionC(ss []Select, filter FilterFunc, cut bool) (*Buc
 		return &root, count
 
 	}
+	if rs != nil && cut {
+		*rs = append(*rs, Reason{})
+		copy((*rs)[index+1:], (*rs)[index:])
+		(*rs)[index] = Reason{Kind: SelectReason, Path: path, Select: ss[0], Count: count}
+	}
 	return nil, 0
 }
 
 // GetMaxSelection returns 'maximal container' -- subgraph which contains
 // any other subgraph satisfying specified selects and filters.
+// Limits are checked in every bucket separately, so the selection
+// from the result can still fail if a limit spans several selected buckets.
+// Use Explain to find out why nil is returned or selection fails.
 func (b Bucket) GetMaxSelection(s SFGroup) (r *Bucket) {
-	var (
-		allowed  = b.findAllowed(s.Filters)
-		excludes = make(map[uint32]bool, len(s.Exclude))
-	)
+	r, _ = b.getMaxSelection(s.Selectors, excludeFunc(b.findAllowed(s.Filters), b.excluded(s)), b.newLimiter(s.Limits))
+	return
+}
+
+// excluded returns indices of all nodes excluded by s
+// either explicitly or by bucket.
+func (b Bucket) excluded(s SFGroup) []uint32 {
+	if len(s.ExcludeBuckets) == 0 {
+		return s.Exclude
+	}
+
+	exclude := append([]uint32(nil), s.Exclude...)
+	for _, o := range s.ExcludeBuckets {
+		exclude = append(exclude, b.findBucketNodes(o).Nodes()...)
+	}
+	return exclude
+}
+
+// findBucketNodes returns nodes of all buckets located at path o.
+// Unlike GetNodesByOption, path can start at any level of b.
+// Invalid paths, including the root one, match no nodes.
+func (b Bucket) findBucketNodes(o string) (nodes Nodes) {
+	if checkBucketPath(o) != nil {
+		return nil
+	}
+
+	props := splitProps(o[len(Separator):])
+	for _, c := range b.findKey(props[0].Key) {
+		if c.Value == props[0].Value {
+			nodes = union(nodes, getNodes(*c, props[1:]))
+		}
+	}
+	return
+}
+
+// checkBucketPath checks if o is a path to non-root bucket:
+// it must start with Separator and consist of non-empty Key:Value pairs.
+func checkBucketPath(o string) error {
+	switch {
+	case o == "":
+		return errors.New("empty path")
+	case o == Separator:
+		return errors.New("root bucket is not allowed")
+	case !strings.HasPrefix(o, Separator):
+		return errors.Errorf("path must start with %s", Separator)
+	}
+
+	for _, kv := range strings.Split(o[len(Separator):], Separator) {
+		k, v, err := splitKV(kv)
+		if err != nil {
+			return errors.Wrapf(err, "bucket %q", kv)
+		}
+		if k == "" || v == "" {
+			return errors.Errorf("bucket %q: empty key or value", kv)
+		}
+	}
+	return nil
+}
 
+// excludeFunc returns FilterFunc which leaves only allowed nodes
+// which are not in exclude.
+func excludeFunc(allowed Nodes, exclude []uint32) FilterFunc {
+	excludes := make(map[uint32]bool, len(exclude))
 	for _, c := range allowed {
 		excludes[c.N] = false
 	}
-	for _, c := range s.Exclude {
+	for _, c := range exclude {
 		excludes[c] = true
 	}
 
-	r, _ = b.getMaxSelection(s.Selectors, func(nodes Nodes) Nodes {
+	return func(nodes Nodes) Nodes {
 		return diff(nodes, excludes)
-	})
-	return
+	}
 }
 
 // GetSelection returns subgraph, satisfying specified selections.
 // It is assumed that all filters were already applied.
 func (b Bucket) GetSelection(ss []Select, pivot []byte) *Bucket {
+	r, _ := b.getSelection(ss, pivot, nil, "", nil)
+	return r
+}
+
+// GetSelectionWithLimits returns subgraph, satisfying specified selections,
+// where at most ls[i].Max nodes are selected from every bucket with key ls[i].Key.
+// Nodes are picked in HRW order skipping the ones exceeding limits.
+// It is assumed that all filters were already applied.
+func (b Bucket) GetSelectionWithLimits(ss []Select, ls []Limit, pivot []byte) *Bucket {
+	r, _ := b.getSelection(ss, pivot, b.newLimiter(ls), "", nil)
+	return r
+}
+
+// ranking keeps HRW order of the selection made in the bucket located at path.
+// Selected sub-buckets are stored in children by their rank, buckets
+// selected by the last select store their nodes by rank.
+type ranking struct {
+	path     string
+	nodes    Nodes
+	children []ranking
+}
+
+// flatten returns all selected nodes, nodes of higher-ranked buckets go first.
+func (r ranking) flatten() Nodes {
+	if len(r.children) == 0 {
+		return r.nodes
+	}
+
+	var nodes Nodes
+	for i := range r.children {
+		nodes = append(nodes, r.children[i].flatten()...)
+	}
+	return nodes
+}
+
+// interleave returns all selected nodes taking them round-robin from
+// the selected sub-buckets, so that every prefix is spread as much as possible.
+func (r ranking) interleave() Nodes {
+	if len(r.children) == 0 {
+		return r.nodes
+	}
+
+	lists := make([]Nodes, 0, len(r.children))
+	for i := range r.children {
+		lists = append(lists, r.children[i].interleave())
+	}
+	return roundRobin(lists)
+}
+
+// roundRobin takes nodes from lists one by one until all lists are exhausted.
+func roundRobin(lists []Nodes) (nodes Nodes) {
+	for i, added := 0, true; added; i++ {
+		added = false
+		for _, l := range lists {
+			if i < len(l) {
+				nodes = append(nodes, l[i])
+				added = true
+			}
+		}
+	}
+	return
+}
+
+// getSelection returns subgraph satisfying ss together with the ranking
+// of the selection. path is the path to b used in the ranking.
+// If rs is not nil, reasons of failed selects and exceeded limits are appended to it.
+func (b Bucket) getSelection(ss []Select, pivot []byte, l *limiter, path string, rs *[]Reason) (*Bucket, *ranking) {
 	var (
 		pivotHash uint64
 		root      = Bucket{Key: b.Key, Value: b.Value}
+		rk        = &ranking{path: path}
 		r         *Bucket
+		rc        *ranking
 		count, c  int
-		cs        []Bucket
+		index     int
+		cs        []pathBucket
 	)
 	if len(pivot) != 0 {
 		pivotHash = hrw.Hash(pivot)
 	}
 
 	if len(ss) == 0 {
+		if l != nil && !l.add(b.nodes...) {
+			if rs != nil {
+				*rs = append(*rs, Reason{Kind: LimitReason, Path: path, Count: l.capacity(b.nodes)})
+			}
+			return nil, nil
+		}
 		root.nodes = b.nodes
 		root.children = b.children
-		return &root
+		rk.nodes = orderNodes(b.nodes, pivot)
+		return &root, rk
 	}
 
 	count = int(ss[0].Count)
 	if ss[0].Key == NodesBucket {
 		if len(b.nodes) < count {
-			return nil
+			if rs != nil {
+				*rs = append(*rs, Reason{Kind: SelectReason, Path: path, Select: ss[0], Count: uint32(len(b.nodes))})
+			}
+			return nil, nil
 		}
 
 		nodes := make(Nodes, len(b.nodes))
@@ -394,31 +685,75 @@ func (b Bucket) GetSelection(ss []Select, pivot []byte) *Bucket {
 		if len(pivot) != 0 {
 			hrw.SortSliceByWeightValue(nodes, nodes.Weights(), pivotHash)
 		}
-		root.nodes = nodes[:count]
-		return &root
-	}
+		if l == nil {
+			root.nodes = nodes[:count]
+			// root.nodes can be sorted during merge, so ranking needs its own copy
+			rk.nodes = append(Nodes(nil), root.nodes...)
+			return &root, rk
+		}
 
-	cs = getChildrenByKey(b, ss[0])
-	if len(pivot) != 0 {
-		if b.weight == 0 {
-			hrw.SortSliceByValue(cs, pivotHash)
-		} else {
-			weights := make([]float64, len(cs))
-			for i := range weights {
-				weights[i] = cs[i].weight
+		for i := range nodes {
+			if len(root.nodes) == count {
+				break
+			}
+			if l.add(nodes[i]) {
+				root.nodes = append(root.nodes, nodes[i])
 			}
-			hrw.SortSliceByWeightValue(cs, weights, pivotHash)
 		}
+		if len(root.nodes) < count {
+			l.remove(root.nodes...)
+			if rs != nil {
+				n := uint32(len(root.nodes))
+				*rs = append(*rs,
+					Reason{Kind: LimitReason, Path: path, Count: n},
+					Reason{Kind: SelectReason, Path: path, Select: ss[0], Count: n})
+			}
+			return nil, nil
+		}
+		rk.nodes = append(Nodes(nil), root.nodes...)
+		return &root, rk
+	}
+
+	if rs != nil {
+		index = len(*rs)
+	}
+
+	cs = getChildrenByKey(b, ss[0], path)
+	if len(pivot) != 0 {
+		b.sortByRank(cs, pivotHash)
 	}
 	for i := 0; i < len(cs); i++ {
-		if r = cs[i].GetSelection(ss[1:], pivot); r != nil {
+		if r, rc = cs[i].b.getSelection(ss[1:], pivot, l, cs[i].path, rs); r != nil {
 			root.Merge(*b.combine(r))
+			rk.children = append(rk.children, *rc)
 			if c++; c == count {
-				return &root
+				return &root, rk
 			}
 		}
 	}
-	return nil
+	if l != nil {
+		l.remove(root.nodes...)
+	}
+	if rs != nil {
+		*rs = append(*rs, Reason{})
+		copy((*rs)[index+1:], (*rs)[index:])
+		(*rs)[index] = Reason{Kind: SelectReason, Path: path, Select: ss[0], Count: uint32(c)}
+	}
+	return nil, nil
+}
+
+// sortByRank sorts buckets cs selected in b in HRW order for pivotHash.
+func (b Bucket) sortByRank(cs []pathBucket, pivotHash uint64) {
+	if b.weight == 0 {
+		hrw.SortSliceByValue(cs, pivotHash)
+		return
+	}
+
+	weights := make([]float64, len(cs))
+	for i := range weights {
+		weights[i] = cs[i].b.weight
+	}
+	hrw.SortSliceByWeightValue(cs, weights, pivotHash)
 }
 
 func (b Bucket) combine(b1 *Bucket) *Bucket {
@@ -507,21 +842,88 @@ func (b *Bucket) UpdateIndices(tr map[uint32]Node) Bucket {
 	}
 }
 
-func getChildrenByKey(b Bucket, s Select) []Bucket {
-	buckets := make([]Bucket, 0, 10)
+// getChildrenByKey returns the closest to b buckets with key s.Key
+// together with their paths. prefix is the path to b.
+func getChildrenByKey(b Bucket, s Select, prefix string) []pathBucket {
+	buckets := make([]pathBucket, 0, 10)
 	for _, c := range b.children {
-		if s.Key == c.Key {
-			buckets = append(buckets, c)
-		} else {
-			buckets = append(buckets, getChildrenByKey(c, s)...)
-		}
+		buckets = append(buckets, c.collectKey(s.Key, prefix+Separator+c.Name())...)
 	}
 	return buckets
 }
 
-// Writes Bucket with this byte structure
+const (
+	// binaryMagic starts every netmap written by Bucket.Write.
+	binaryMagic = "NMAP"
+	// binaryVersion is the current version of binary netmap format.
+	binaryVersion uint16 = 1
+)
+
+// Write writes Bucket with this byte structure
+// [Magic][Version][lnBody][Body][Checksum]
+// where Magic is "NMAP", Checksum is CRC32 (IEEE) of all preceding bytes
+// and Body is
+// [Schema][Tree][lnMetrics][Metrics1]...[MetricsN]
+// where Schema lists all metric names
+// [lnNames][lnName1][Name1]...[lnNameK][NameK],
+// Tree is
 // [lnName][Name][lnNodes][Node1]...[NodeN][lnSubprops][sub1]...[subN]
+// and Metrics is
+// [N][lnM][Index1][Value1]...[IndexM][ValueM]
+// where Index is the position of metric name in Schema.
 func (b Bucket) Write(w io.Writer) error {
+	var (
+		err  error
+		body = new(bytes.Buffer)
+		h    = crc32.NewIEEE()
+		hw   = io.MultiWriter(w, h)
+	)
+
+	if err = b.writeBody(body); err != nil {
+		return err
+	}
+
+	if _, err = io.WriteString(hw, binaryMagic); err != nil {
+		return err
+	}
+	if err = binary.Write(hw, binary.BigEndian, binaryVersion); err != nil {
+		return err
+	}
+	if err = binary.Write(hw, binary.BigEndian, uint32(body.Len())); err != nil {
+		return err
+	}
+	if _, err = hw.Write(body.Bytes()); err != nil {
+		return err
+	}
+	return binary.Write(w, binary.BigEndian, h.Sum32())
+}
+
+func (b Bucket) writeBody(w io.Writer) error {
+	var (
+		err   error
+		nodes = b.Nodelist()
+		names = nodes.metricNames()
+	)
+
+	if err = binary.Write(w, binary.BigEndian, int32(len(names))); err != nil {
+		return err
+	}
+	for _, name := range names {
+		if err = binary.Write(w, binary.BigEndian, int32(len(name))); err != nil {
+			return err
+		}
+		if err = binary.Write(w, binary.BigEndian, []byte(name)); err != nil {
+			return err
+		}
+	}
+
+	if err = b.writeTree(w); err != nil {
+		return err
+	}
+	return nodes.writeIndexedMetrics(w, names)
+}
+
+func (b Bucket) writeTree(w io.Writer) error {
 	var err error
 
 	// writing name
@@ -541,7 +943,7 @@ func (b Bucket) Write(w io.Writer) error {
 		return err
 	}
 	for i := range b.children {
-		if err = b.children[i].Write(w); err != nil {
+		if err = b.children[i].writeTree(w); err != nil {
 			return err
 		}
 	}
@@ -549,24 +951,144 @@ func (b Bucket) Write(w io.Writer) error {
 	return nil
 }
 
-// Read reads Bucket in serialized form:
-// [lnName][Name][lnNodes][Node1]...[NodeN][lnSubprops][sub1]...[subN]
+// Read reads Bucket written by Write. Checksum is verified before
+// the body is parsed. Legacy data without header consisting only of [Tree]
+// is also supported, such netmap has no metrics.
 func (b *Bucket) Read(r io.Reader) error {
-	var ln int32
-	var err error
-	if err = binary.Read(r, binary.BigEndian, &ln); err != nil {
+	magic := make([]byte, len(binaryMagic))
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return errors.Wrap(unexpectedEOF(err), "can't read header")
+	}
+	if string(magic) != binaryMagic {
+		return b.readLegacy(io.MultiReader(bytes.NewReader(magic), r))
+	}
+
+	var (
+		err     error
+		version uint16
+		ln      uint32
+		sum     uint32
+		h       = crc32.NewIEEE()
+		tr      = io.TeeReader(r, h)
+		body    = new(bytes.Buffer)
+	)
+
+	_, _ = h.Write(magic)
+	if err = binary.Read(tr, binary.BigEndian, &version); err != nil {
+		return errors.Wrap(unexpectedEOF(err), "can't read header")
+	}
+	if version != binaryVersion {
+		return errors.Errorf("unsupported netmap format version %d", version)
+	}
+	if err = binary.Read(tr, binary.BigEndian, &ln); err != nil {
+		return errors.Wrap(unexpectedEOF(err), "can't read header")
+	}
+
+	// buffer grows while reading, so a corrupted length can't cause huge allocation
+	if _, err = io.CopyN(body, tr, int64(ln)); err != nil {
+		return errors.Wrap(unexpectedEOF(err), "can't read body")
+	}
+	if err = binary.Read(r, binary.BigEndian, &sum); err != nil {
+		return errors.Wrap(unexpectedEOF(err), "can't read checksum")
+	}
+	if sum != h.Sum32() {
+		return errors.Errorf("checksum mismatch: expected %08x, got %08x", sum, h.Sum32())
+	}
+
+	var (
+		nb Bucket
+		br = bytes.NewReader(body.Bytes())
+	)
+	if err = nb.readBody(br); err != nil {
+		return errors.Wrap(unexpectedEOF(err), "invalid body")
+	}
+	if br.Len() != 0 {
+		return errors.Errorf("invalid body: %d unexpected trailing bytes", br.Len())
+	}
+	*b = nb
+	return nil
+}
+
+func (b *Bucket) readBody(r io.Reader) error {
+	var (
+		err   error
+		count int32
+		names []string
+	)
+
+	if err = binary.Read(r, binary.BigEndian, &count); err != nil {
 		return err
 	}
-	name := make([]byte, ln)
-	lnE, err := r.Read(name)
+	if count < 0 {
+		return errors.Errorf("invalid number of metric names %d", count)
+	}
+	for ; count > 0; count-- {
+		name, err := readString(r)
+		if err != nil {
+			return err
+		}
+		names = append(names, name)
+	}
+
+	if err = b.readTree(r); err != nil {
+		return err
+	}
+
+	if err = binary.Read(r, binary.BigEndian, &count); err != nil {
+		return err
+	}
+	ms, err := readIndexedMetrics(r, count, names)
 	if err != nil {
 		return err
 	}
-	if int32(lnE) != ln {
-		return errors.New("unmarshaller error: cannot read name")
+	b.setMetrics(ms)
+	return nil
+}
+
+// readLegacy reads Bucket without header and metrics.
+// Nothing is read after the tree, so several buckets can be read from one stream.
+func (b *Bucket) readLegacy(r io.Reader) error {
+	var nb Bucket
+	if err := nb.readTree(r); err != nil {
+		return errors.Wrap(unexpectedEOF(err), "invalid legacy netmap")
 	}
+	*b = nb
+	return nil
+}
 
-	b.Key, b.Value, _ = splitKV(string(name))
+// unexpectedEOF converts io.EOF to io.ErrUnexpectedEOF for
+// the data which can't end at the point of reading.
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+func readString(r io.Reader) (string, error) {
+	var ln int32
+	if err := binary.Read(r, binary.BigEndian, &ln); err != nil {
+		return "", err
+	}
+	if ln < 0 {
+		return "", errors.Errorf("invalid string length %d", ln)
+	}
+	// ln is not trusted yet, so the string is not preallocated
+	buf := new(bytes.Buffer)
+	if _, err := io.CopyN(buf, r, int64(ln)); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func (b *Bucket) readTree(r io.Reader) error {
+	var ln int32
+	name, err := readString(r)
+	if err != nil {
+		return err
+	}
+
+	b.Key, b.Value, _ = splitKV(name)
 
 	// reading node list
 	if err = b.nodes.Read(r); err != nil {
@@ -576,18 +1098,225 @@ func (b *Bucket) Read(r io.Reader) error {
 	if err = binary.Read(r, binary.BigEndian, &ln); err != nil {
 		return err
 	}
-	if ln > 0 {
-		b.children = make([]Bucket, ln)
-		for i := range b.children {
-			if err = b.children[i].Read(r); err != nil {
+	// children are appended one by one for the same reason as in readString
+	for ; ln > 0; ln-- {
+		var c Bucket
+		if err = c.readTree(r); err != nil {
+			return err
+		}
+		b.children = append(b.children, c)
+	}
+
+	return nil
+}
+
+func (n Nodes) writeMetrics(w io.Writer) error {
+	var (
+		err   error
+		count int32
+	)
+
+	for i := range n {
+		if n[i].M.Len() != 0 {
+			count++
+		}
+	}
+	if err = binary.Write(w, binary.BigEndian, count); err != nil {
+		return err
+	}
+
+	for i := range n {
+		if n[i].M.Len() == 0 {
+			continue
+		}
+
+		names := n[i].M.Names()
+
+		if err = binary.Write(w, binary.BigEndian, n[i].N); err != nil {
+			return err
+		}
+		if err = binary.Write(w, binary.BigEndian, int32(len(names))); err != nil {
+			return err
+		}
+		for _, name := range names {
+			if err = binary.Write(w, binary.BigEndian, int32(len(name))); err != nil {
+				return err
+			}
+			if err = binary.Write(w, binary.BigEndian, []byte(name)); err != nil {
+				return err
+			}
+			if err = binary.Write(w, binary.BigEndian, n[i].Metric(name)); err != nil {
 				return err
 			}
 		}
 	}
+	return nil
+}
+
+func (n *Nodes) readMetrics(r io.Reader) error {
+	var count int32
+	if err := binary.Read(r, binary.BigEndian, &count); err != nil {
+		return err
+	}
 
+	ms, err := readMetrics(r, count)
+	if err != nil {
+		return err
+	}
+	for i := range *n {
+		if m, ok := ms[(*n)[i].N]; ok {
+			(*n)[i].M = m
+		}
+	}
 	return nil
 }
 
+func readMetrics(r io.Reader, count int32) (map[uint32]Metrics, error) {
+	var err error
+
+	ms := make(map[uint32]Metrics)
+	for ; count > 0; count-- {
+		var (
+			n  uint32
+			ln int32
+		)
+		if err = binary.Read(r, binary.BigEndian, &n); err != nil {
+			return nil, err
+		}
+		if err = binary.Read(r, binary.BigEndian, &ln); err != nil {
+			return nil, err
+		}
+
+		m := make(map[string]float64)
+		for ; ln > 0; ln-- {
+			var value float64
+			name, err := readString(r)
+			if err != nil {
+				return nil, err
+			}
+			if err = binary.Read(r, binary.BigEndian, &value); err != nil {
+				return nil, err
+			}
+			m[name] = value
+		}
+		ms[n] = NewMetrics(m)
+	}
+	return ms, nil
+}
+
+func (n Nodes) metricNames() []string {
+	var (
+		names []string
+		seen  = make(map[string]bool)
+	)
+	for i := range n {
+		for _, name := range n[i].M.Names() {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (n Nodes) writeIndexedMetrics(w io.Writer, names []string) error {
+	var (
+		err   error
+		count int32
+		index = make(map[string]int32, len(names))
+	)
+
+	for i, name := range names {
+		index[name] = int32(i)
+	}
+	for i := range n {
+		if n[i].M.Len() != 0 {
+			count++
+		}
+	}
+	if err = binary.Write(w, binary.BigEndian, count); err != nil {
+		return err
+	}
+
+	for i := range n {
+		if n[i].M.Len() == 0 {
+			continue
+		}
+
+		ms := n[i].M.Names()
+
+		if err = binary.Write(w, binary.BigEndian, n[i].N); err != nil {
+			return err
+		}
+		if err = binary.Write(w, binary.BigEndian, int32(len(ms))); err != nil {
+			return err
+		}
+		for _, name := range ms {
+			if err = binary.Write(w, binary.BigEndian, index[name]); err != nil {
+				return err
+			}
+			if err = binary.Write(w, binary.BigEndian, n[i].Metric(name)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func readIndexedMetrics(r io.Reader, count int32, names []string) (map[uint32]Metrics, error) {
+	var err error
+
+	ms := make(map[uint32]Metrics)
+	for ; count > 0; count-- {
+		var (
+			n  uint32
+			ln int32
+		)
+		if err = binary.Read(r, binary.BigEndian, &n); err != nil {
+			return nil, err
+		}
+		if err = binary.Read(r, binary.BigEndian, &ln); err != nil {
+			return nil, err
+		}
+
+		m := make(map[string]float64)
+		for ; ln > 0; ln-- {
+			var (
+				i     int32
+				value float64
+			)
+			if err = binary.Read(r, binary.BigEndian, &i); err != nil {
+				return nil, err
+			}
+			if i < 0 || int(i) >= len(names) {
+				return nil, errors.Errorf("node %d: unknown metric index %d", n, i)
+			}
+			if err = binary.Read(r, binary.BigEndian, &value); err != nil {
+				return nil, err
+			}
+			m[names[i]] = value
+		}
+		ms[n] = NewMetrics(m)
+	}
+	return ms, nil
+}
+
+func (b *Bucket) setMetrics(ms map[uint32]Metrics) {
+	if len(ms) == 0 {
+		return
+	}
+	for i := range b.nodes {
+		if m, ok := ms[b.nodes[i].N]; ok {
+			b.nodes[i].M = m
+		}
+	}
+	for i := range b.children {
+		b.children[i].setMetrics(ms)
+	}
+}
+
 // MarshalBinary implements the encoding.BinaryMarshaler interface.
 func (b Bucket) MarshalBinary() ([]byte, error) {
 	buf := new(bytes.Buffer)
@@ -598,12 +1327,17 @@ func (b Bucket) MarshalBinary() ([]byte, error) {
 }
 
 // UnmarshalBinary implements the encoding.BinaryUnmarshaler interface.
-func (b *Bucket) UnmarshalBinary(data []byte) (err error) {
-	buf := bytes.NewBuffer(data)
-	if err = b.Read(buf); err == io.EOF {
-		return nil
+// Trailing bytes after the versioned format are an error. Legacy data
+// is decoded as before and trailing bytes are ignored.
+func (b *Bucket) UnmarshalBinary(data []byte) error {
+	r := bytes.NewReader(data)
+	if err := b.Read(r); err != nil {
+		return err
 	}
-	return
+	if r.Len() != 0 && bytes.HasPrefix(data, []byte(binaryMagic)) {
+		return errors.Errorf("%d unexpected trailing bytes", r.Len())
+	}
+	return nil
 }
 
 // Name return b's short string identifier.
@@ -639,7 +1373,7 @@ func (b Bucket) Children() []Bucket {
 
 // AddNode adds node n with options opts to b.
 func (b *Bucket) AddNode(n uint32, opts ...string) error {
-	return b.addNode(Node{n, 0, 0}, opts...)
+	return b.addNode(Node{N: n}, opts...)
 }
 
 // AddStrawNode adds straw node n with options opts to b.
@@ -706,6 +1440,111 @@ func (b *Bucket) AddChild(c Bucket) {
 	b.children = append(b.children, c)
 }
 
+// RemoveNode removes node n from b and all of its subbuckets.
+// Subbuckets which are left without nodes are pruned.
+func (b *Bucket) RemoveNode(n uint32) error {
+	if !b.removeNode(n) {
+		return errors.Errorf("node %d not found", n)
+	}
+	return nil
+}
+
+func (b *Bucket) removeNode(n uint32) bool {
+	var (
+		found    bool
+		children []Bucket
+	)
+
+	// nodes can be shared between buckets, so new slice must be allocated
+	if i := search(b.nodes, n); i < len(b.nodes) {
+		found = true
+		if len(b.nodes) == 1 {
+			b.nodes = nil
+		} else {
+			nodes := make(Nodes, 0, len(b.nodes)-1)
+			nodes = append(nodes, b.nodes[:i]...)
+			b.nodes = append(nodes, b.nodes[i+1:]...)
+		}
+	}
+
+	for i := range b.children {
+		if b.children[i].removeNode(n) {
+			found = true
+			if len(b.children[i].Nodelist()) == 0 {
+				continue
+			}
+		}
+		children = append(children, b.children[i])
+	}
+	if found {
+		b.children = children
+	}
+	return found
+}
+
+// UpdateNode moves node n to options opts.
+// Placement of n under every top-level key mentioned in opts is replaced,
+// options with other top-level keys are left intact.
+// Bucket is not modified if error is returned.
+func (b *Bucket) UpdateNode(n uint32, opts ...string) error {
+	i := search(b.nodes, n)
+	if i == len(b.nodes) {
+		return errors.Errorf("node %d not found", n)
+	}
+
+	nb, keys, err := optionsBucket(b.nodes[i], opts)
+	if err != nil {
+		return err
+	}
+
+	b.dropOptions(n, func(key string) bool {
+		_, ok := keys[key]
+		return ok
+	})
+	b.Merge(nb)
+	return nil
+}
+
+// optionsBucket returns bucket containing node n with all options opts
+// together with top-level buckets of every option key.
+// Error is returned if some of the options conflict with each other.
+func optionsBucket(n Node, opts []string) (Bucket, map[string]Bucket, error) {
+	var (
+		nb   Bucket
+		keys = make(map[string]Bucket, len(opts))
+	)
+
+	for _, o := range opts {
+		var ob Bucket
+		if err := ob.addNode(n, o); err != nil {
+			return nb, nil, err
+		}
+		for _, c := range ob.children {
+			kb, ok := keys[c.Key]
+			if ok && kb.CheckConflicts(ob) {
+				return nb, nil, errors.Errorf("node %d has conflicting options for key %s", n.N, c.Key)
+			}
+			kb.Merge(ob.Copy())
+			keys[c.Key] = kb
+		}
+		nb.Merge(ob)
+	}
+	return nb, keys, nil
+}
+
+// dropOptions removes node n from all direct children of b
+// which key satisfies drop. Node itself is left in b.
+func (b *Bucket) dropOptions(n uint32, drop func(key string) bool) {
+	children := make([]Bucket, 0, len(b.children))
+	for i := range b.children {
+		if drop(b.children[i].Key) && b.children[i].removeNode(n) && len(b.children[i].Nodelist()) == 0 {
+			continue
+		}
+		children = append(children, b.children[i])
+	}
+	b.children = children
+}
+
 func splitProps(o string) []Bucket {
 	ss := strings.Split(o, Separator)
 	props := make([]Bucket, 0, 10)
@@ -1,13 +1,19 @@
 package netmap
 
 import (
+	"bytes"
+	"encoding/binary"
 	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
 	"math"
 	"math/rand"
 	"strconv"
 	"testing"
 	"time"
 
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/require"
 )
 
@@ -622,6 +628,106 @@ func TestNetMap_AddNode(t *testing.T) {
 	require.Equal(t, []uint32{1, 2, 3}, ns.Nodes())
 }
 
+func TestBucket_RemoveNode(t *testing.T) {
+	var (
+		root, exp Bucket
+		err       error
+	)
+
+	root, err = newRoot(
+		bucket{"/Location:Europe/Country:France/City:Paris", []uint32{1, 3}},
+		bucket{"/Location:Europe/Country:Germany", []uint32{7}},
+		bucket{"/Location:Asia/Country:Korea", []uint32{2}},
+	)
+	require.NoError(t, err)
+
+	t.Run("remove from multiple buckets", func(t *testing.T) {
+		exp, err = newRoot(
+			bucket{"/Location:Europe/Country:France/City:Paris", []uint32{3}},
+			bucket{"/Location:Europe/Country:Germany", []uint32{7}},
+			bucket{"/Location:Asia/Country:Korea", []uint32{2}},
+		)
+		require.NoError(t, err)
+
+		require.NoError(t, root.RemoveNode(1))
+		require.Equal(t, exp, root)
+		require.True(t, root.IsValid())
+	})
+
+	t.Run("prune empty buckets", func(t *testing.T) {
+		exp, err = newRoot(
+			bucket{"/Location:Europe/Country:France/City:Paris", []uint32{3}},
+			bucket{"/Location:Europe/Country:Germany", []uint32{7}},
+		)
+		require.NoError(t, err)
+
+		require.NoError(t, root.RemoveNode(2))
+		require.Equal(t, exp, root)
+		require.True(t, root.IsValid())
+	})
+
+	t.Run("missing node", func(t *testing.T) {
+		require.Error(t, root.RemoveNode(2))
+		require.Error(t, root.RemoveNode(42))
+		require.Equal(t, exp, root)
+	})
+}
+
+func TestBucket_UpdateNode(t *testing.T) {
+	var (
+		root, exp Bucket
+		err       error
+	)
+
+	root, err = newRoot(
+		bucket{"/Location:Europe/Country:Germany", []uint32{1, 2}},
+		bucket{"/Location:Europe/Country:Austria", []uint32{3}},
+		bucket{"/Trust:10", []uint32{1}},
+	)
+	require.NoError(t, err)
+	require.NoError(t, root.Validate())
+
+	t.Run("move to sibling", func(t *testing.T) {
+		exp, err = newRoot(
+			bucket{"/Location:Europe/Country:Germany", []uint32{2}},
+			bucket{"/Location:Europe/Country:Austria", []uint32{1, 3}},
+			bucket{"/Trust:10", []uint32{1}},
+		)
+		require.NoError(t, err)
+
+		require.NoError(t, root.UpdateNode(1, "/Location:Europe/Country:Austria"))
+		require.Equal(t, exp, root)
+	})
+
+	t.Run("move to new bucket", func(t *testing.T) {
+		exp, err = newRoot(
+			bucket{"/Location:Europe/Country:Austria", []uint32{1, 3}},
+			bucket{"/Trust:10", []uint32{1}},
+			bucket{"/Location:Asia/Country:Japan", []uint32{2}},
+		)
+		require.NoError(t, err)
+
+		require.NoError(t, root.UpdateNode(2, "/Location:Asia/Country:Japan"))
+		require.Equal(t, exp, root)
+		require.NoError(t, root.Validate())
+	})
+
+	t.Run("conflicting options", func(t *testing.T) {
+		err = root.UpdateNode(1, "/Location:Europe/Country:Germany", "/Location:Asia/Country:Japan")
+		require.Error(t, err)
+
+		err = root.UpdateNode(1, "/Location:Europe/Country:Germany", "/Location:Europe/Country:Spain")
+		require.Error(t, err)
+
+		require.Equal(t, exp, root)
+	})
+
+	t.Run("missing node", func(t *testing.T) {
+		require.Error(t, root.UpdateNode(42, "/Location:Asia/Country:Japan"))
+		require.Equal(t, exp, root)
+	})
+}
+
 func TestBucket_MarshalBinary(t *testing.T) {
 	var (
 		before, after Bucket
@@ -642,6 +748,182 @@ func TestBucket_MarshalBinary(t *testing.T) {
 	require.Equal(t, before, after)
 }
 
+func TestBucket_MarshalBinaryMetrics(t *testing.T) {
+	var (
+		before, after Bucket
+		data          []byte
+		err           error
+	)
+
+	before, err = newStrawRoot(
+		strawBucket{"/Location:Europe/Country:Germany", Nodes{
+			{N: 1, C: 10, P: 1, M: NewMetrics(map[string]float64{"uptime": 0.99, "bandwidth": 100})},
+			{N: 2, C: 20, P: 2},
+		}},
+		strawBucket{"/Location:Asia", Nodes{
+			{N: 3, C: 30, P: 3, M: NewMetrics(map[string]float64{"reputation": -1.5})},
+		}},
+	)
+	require.NoError(t, err)
+
+	data, err = before.MarshalBinary()
+	require.NoError(t, err)
+	err = after.UnmarshalBinary(data)
+	require.NoError(t, err)
+	require.Equal(t, before, after)
+
+	t.Run("without metrics section", func(t *testing.T) {
+		var (
+			b   Bucket
+			buf = new(bytes.Buffer)
+		)
+
+		require.NoError(t, before.writeTree(buf))
+		require.NoError(t, b.UnmarshalBinary(buf.Bytes()))
+		require.Equal(t, before.Nodelist().Nodes(), b.Nodelist().Nodes())
+		require.Zero(t, b.Nodelist()[0].M.Len())
+	})
+
+	t.Run("truncated metrics section", func(t *testing.T) {
+		var b Bucket
+		require.Error(t, b.UnmarshalBinary(data[:len(data)-4]))
+	})
+}
+
+func TestBucket_MarshalBinaryFormat(t *testing.T) {
+	before, err := newStrawRoot(
+		strawBucket{"/Location:Europe/Country:Germany", Nodes{
+			{N: 1, C: 10, P: 1, M: NewMetrics(map[string]float64{"uptime": 0.99, "bandwidth": 100})},
+			{N: 2, C: 20, P: 2, M: NewMetrics(map[string]float64{"uptime": 0.5})},
+		}},
+		strawBucket{"/Location:Asia", Nodes{{N: 3, C: 30, P: 3}}},
+	)
+	require.NoError(t, err)
+
+	data, err := before.MarshalBinary()
+	require.NoError(t, err)
+	require.Equal(t, []byte(binaryMagic), data[:4])
+	require.Equal(t, binaryVersion, binary.BigEndian.Uint16(data[4:]))
+	require.Equal(t, crc32.ChecksumIEEE(data[:len(data)-4]), binary.BigEndian.Uint32(data[len(data)-4:]))
+
+	t.Run("legacy stream", func(t *testing.T) {
+		other, err := newRoot(bucket{"/Location:Europe", []uint32{1, 2}})
+		require.NoError(t, err)
+
+		buf := new(bytes.Buffer)
+		require.NoError(t, before.writeTree(buf))
+		require.NoError(t, other.writeTree(buf))
+
+		var b1, b2 Bucket
+		require.NoError(t, b1.Read(buf))
+		require.NoError(t, b2.Read(buf))
+		require.Equal(t, before.Nodelist().Nodes(), b1.Nodelist().Nodes())
+		require.Zero(t, b1.Nodelist()[0].M.Len())
+		require.Equal(t, other, b2)
+		require.Zero(t, buf.Len())
+	})
+
+	t.Run("legacy fixture", func(t *testing.T) {
+		// testdata/legacy is written by MarshalBinary of the netmap version
+		// preceding the versioned format.
+		data, err := ioutil.ReadFile("testdata/legacy")
+		require.NoError(t, err)
+
+		expected := Nodes{{N: 1, C: 10, P: 1}, {N: 2, C: 20, P: 2}, {N: 3, C: 30, P: 3}, {N: 4, C: 40, P: 4}}
+
+		var b Bucket
+		require.NoError(t, b.UnmarshalBinary(data))
+		require.Equal(t, expected, b.Nodelist())
+		require.NoError(t, b.Validate())
+		require.ElementsMatch(t, []string{"/Location:Europe/Country:Germany/City:Berlin", "/Trust:10"}, b.GetOptionsByNode(1))
+		require.ElementsMatch(t, []string{"/Location:Europe/Country:France/City:Paris"}, b.GetOptionsByNode(3))
+		require.ElementsMatch(t, []string{"/Location:Asia/Country:Korea/City:Seoul", "/Trust:10"}, b.GetOptionsByNode(4))
+
+		var nb Bucket
+		require.NoError(t, nb.UnmarshalBinary(append(data, 1, 2, 3)))
+		require.Equal(t, b, nb)
+
+		// legacy data converts to the versioned format
+		data, err = b.MarshalBinary()
+		require.NoError(t, err)
+		require.Equal(t, []byte(binaryMagic), data[:4])
+		require.Error(t, nb.UnmarshalBinary(append(data, 1, 2, 3)))
+	})
+
+	t.Run("legacy negative length", func(t *testing.T) {
+		data := []byte{0xFF, 0xFF, 0xFF, 0xFF}
+
+		var b Bucket
+		require.Error(t, b.UnmarshalBinary(data))
+	})
+
+	t.Run("examples", func(t *testing.T) {
+		for _, name := range []string{"examples/demo", "examples/map2"} {
+			data, err := ioutil.ReadFile(name)
+			require.NoError(t, err)
+
+			var b Bucket
+			require.NoError(t, b.UnmarshalBinary(data), name)
+			require.NotEmpty(t, b.Nodelist(), name)
+			require.NoError(t, b.Validate(), name)
+		}
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		for i := 0; i < len(data); i++ {
+			var b Bucket
+			require.Error(t, b.UnmarshalBinary(data[:i]), "length %d", i)
+		}
+	})
+
+	t.Run("corrupted", func(t *testing.T) {
+		for i := range data {
+			corrupted := append([]byte{}, data...)
+			corrupted[i] ^= 0xFF
+
+			var b Bucket
+			require.Error(t, b.UnmarshalBinary(corrupted), "byte %d", i)
+		}
+
+		corrupted := append([]byte{}, data...)
+		corrupted[10] ^= 0xFF
+
+		var b Bucket
+		err := b.UnmarshalBinary(corrupted)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "checksum mismatch")
+		require.Equal(t, Bucket{}, b)
+	})
+
+	t.Run("unsupported version", func(t *testing.T) {
+		corrupted := append([]byte{}, data...)
+		binary.BigEndian.PutUint16(corrupted[4:], binaryVersion+1)
+
+		var b Bucket
+		err := b.UnmarshalBinary(corrupted)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unsupported netmap format version")
+	})
+
+	t.Run("trailing bytes", func(t *testing.T) {
+		var b Bucket
+		require.Error(t, b.UnmarshalBinary(append(data, 0)))
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		buf := new(bytes.Buffer)
+		require.NoError(t, before.Write(buf))
+		require.NoError(t, Bucket{}.Write(buf))
+
+		var b1, b2 Bucket
+		require.NoError(t, b1.Read(buf))
+		require.NoError(t, b2.Read(buf))
+		require.Equal(t, before, b1)
+		require.Equal(t, Bucket{}, b2)
+		require.Equal(t, io.ErrUnexpectedEOF, errors.Cause(b2.Read(buf)))
+	})
+}
+
 func TestBucket_Nodelist(t *testing.T) {
 	var (
 		nodes   Nodes
@@ -996,6 +1278,134 @@ func TestBucket_FindNodes(t *testing.T) {
 	require.Equal(t, ns, nscopy)
 }
 
+func TestBucket_ExcludeBuckets(t *testing.T) {
+	root, err := newRoot(
+		bucket{"/Location:Europe/Country:Germany/City:Berlin", []uint32{1, 2}},
+		bucket{"/Location:Europe/Country:Russia/City:Moscow", []uint32{3, 4}},
+		bucket{"/Location:Asia/Country:China/City:Beijing", []uint32{5}},
+		bucket{"/Location:Asia/Country:Russia/City:Vladivostok", []uint32{6}},
+		bucket{"/Location:Asia/Country:Japan/City:Tokyo", []uint32{7, 8}},
+	)
+	require.NoError(t, err)
+
+	s := SFGroup{Selectors: []Select{{Key: NodesBucket, Count: 1}}}
+
+	s.ExcludeBuckets = []string{"/Country:Russia"}
+	require.Equal(t, []uint32{1, 2, 5, 7, 8}, root.GetMaxSelection(s).Nodelist().Nodes())
+
+	s.ExcludeBuckets = []string{"/Location:Asia/Country:China", "/City:Berlin"}
+	require.Equal(t, []uint32{3, 4, 6, 7, 8}, root.GetMaxSelection(s).Nodelist().Nodes())
+
+	s.Exclude = []uint32{3}
+	s.ExcludeBuckets = []string{"/Location:Asia", "/Country:Germany"}
+	require.Equal(t, []uint32{4}, root.GetMaxSelection(s).Nodelist().Nodes())
+
+	// unknown and malformed paths, including the root one, exclude nothing
+	s.Exclude = nil
+	s.ExcludeBuckets = []string{"/Country:France", "Country:Russia", "", "/", "/:", "/Country:"}
+	require.Equal(t, root.Nodelist(), root.GetMaxSelection(s).Nodelist())
+
+	s.ExcludeBuckets = []string{"/Location:Europe", "/Location:Asia"}
+	require.Nil(t, root.GetMaxSelection(s))
+}
+
+func TestBucket_NodeFilters(t *testing.T) {
+	root, err := newStrawRoot(
+		strawBucket{"/Location:Europe/Country:Germany", Nodes{
+			{N: 1, C: 1000, P: 50},
+			{N: 2, C: 500, P: 10, M: NewMetrics(map[string]float64{"uptime": 0.99})},
+		}},
+		strawBucket{"/Location:Europe/Country:Austria", Nodes{
+			{N: 3, C: 2000, P: 150, M: NewMetrics(map[string]float64{"uptime": 0.5})},
+			{N: 4, C: 1500, P: 100, M: NewMetrics(map[string]float64{"uptime": 0.95})},
+		}},
+	)
+	require.NoError(t, err)
+
+	s := SFGroup{Selectors: []Select{{Key: NodesBucket, Count: 1}}}
+
+	s.Filters = []Filter{{Key: CapacityFilterKey, F: FilterGE(1000)}}
+	require.Equal(t, []uint32{1, 3, 4}, root.GetMaxSelection(s).Nodelist().Nodes())
+
+	s.Filters = append(s.Filters, Filter{Key: PriceFilterKey, F: FilterLE(100)})
+	require.Equal(t, []uint32{1, 4}, root.GetMaxSelection(s).Nodelist().Nodes())
+
+	s.Filters = []Filter{{Key: NodeFilterKey("uptime"), F: NewFilter(Operation_GT, "0.9")}}
+	require.Equal(t, []uint32{2, 4}, root.GetMaxSelection(s).Nodelist().Nodes())
+
+	s.Filters = []Filter{
+		{Key: NodeFilterKey("uptime"), F: FilterOR(FilterEQ("0"), FilterEQ("0.5"))},
+		{Key: "Country", F: FilterEQ("Germany")},
+	}
+	require.Equal(t, []uint32{1}, root.GetMaxSelection(s).Nodelist().Nodes())
+
+	s.Filters = []Filter{{Key: CapacityFilterKey, F: NewTypedFilter(Operation_GE, Type_Size, "1.5KB")}}
+	require.Equal(t, []uint32{3, 4}, root.GetMaxSelection(s).Nodelist().Nodes())
+
+	s.Filters = []Filter{{Key: CapacityFilterKey, F: NewTypedFilter(Operation_GE, Type_Version, "1.0")}}
+	require.Nil(t, root.GetMaxSelection(s))
+
+	s.Filters = []Filter{{Key: CapacityFilterKey, F: FilterGT(2000)}}
+	require.Nil(t, root.GetMaxSelection(s))
+	require.EqualError(t, root.Explain(s), "selection failed: "+
+		"filter Node.C GT 2000 left 0 nodes; "+
+		"select 1 Node needed 1 node under / but found 0")
+}
+
+func TestBucket_FindNodesOrdered(t *testing.T) {
+	root, err := newRoot(
+		bucket{"/Location:Europe/Country:Germany", []uint32{1, 2, 3, 4, 5}},
+		bucket{"/Location:Europe/Country:Austria", []uint32{6, 7, 8}},
+	)
+	require.NoError(t, err)
+
+	all := SFGroup{Selectors: []Select{{Key: NodesBucket, Count: 8}}}
+	for _, pivot := range SamplePivots(20) {
+		ordered := root.FindNodesOrdered(pivot, all)
+		require.ElementsMatch(t, root.FindNodes(pivot, all), ordered)
+
+		// prefix of ordered vector must be the same as selected nodes
+		for k := uint32(1); k < 8; k++ {
+			s := SFGroup{Selectors: []Select{{Key: NodesBucket, Count: k}}}
+			require.ElementsMatch(t, root.FindNodes(pivot, s), ordered[:k])
+		}
+	}
+
+	require.Nil(t, root.FindNodesOrdered(nil, SFGroup{Selectors: []Select{{Key: NodesBucket, Count: 9}}}))
+
+	t.Run("weighted", func(t *testing.T) {
+		root, err := newStrawRoot(
+			strawBucket{"/Location:Europe/Country:Germany", Nodes{
+				{N: 1, C: 10, P: 1}, {N: 2, C: 100, P: 5}, {N: 3, C: 1, P: 1}, {N: 4, C: 50, P: 50},
+			}},
+			strawBucket{"/Location:Europe/Country:Austria", Nodes{
+				{N: 5, C: 70, P: 2}, {N: 6, C: 5, P: 30}, {N: 7, C: 20, P: 3},
+			}},
+			strawBucket{"/Location:Asia/Country:Korea", Nodes{
+				{N: 8, C: 40, P: 10}, {N: 9, C: 3, P: 2}, {N: 10, C: 90, P: 1},
+			}},
+		)
+		require.NoError(t, err)
+
+		s := SFGroup{Selectors: []Select{{Key: "Country", Count: 2}, {Key: NodesBucket, Count: 2}}}
+		for _, pivot := range SamplePivots(50) {
+			ordered := root.FindNodesOrdered(pivot, s)
+			require.Len(t, ordered, 4)
+			require.ElementsMatch(t, root.FindNodes(pivot, s), ordered)
+
+			// nodes of the same bucket go together in the order
+			// computed over all nodes of the bucket, not over the selected ones
+			for i := 0; i < len(ordered); i += 2 {
+				path := root.GetOptionsByNode(ordered[i].N)
+				require.Len(t, path, 1)
+
+				all := orderNodes(root.GetNodesByOption(path[0]), pivot)
+				require.Equal(t, all[:2], ordered[i:i+2])
+			}
+		}
+	})
+}
+
 func TestNodes_Weight(t *testing.T) {
 	var N Nodes
 	t.Run("empty weights", func(t *testing.T) {
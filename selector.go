@@ -1,8 +1,14 @@
 package netmap
 
 import (
+	"fmt"
+	"path"
+	"regexp"
 	"strconv"
+	"strings"
+	"sync"
 
+	"github.com/pkg/errors"
 	// used by protoc
 	_ "github.com/gogo/protobuf/proto"
 )
@@ -16,8 +22,16 @@ func (f Filter) Check(b Bucket) bool {
 }
 
 // Check returns result of applying sf to value.
-// For numeric comparisons, value is parsed to int64.
+// For String type numeric comparisons parse value to int64
+// and non-parsable values are considered satisfied.
+// For other types value which can't be parsed according to sf.Type
+// doesn't match. Use Match to get the reason.
 func (sf SimpleFilter) Check(value string) bool {
+	if sf.Type != Type_String {
+		ok, err := sf.Match(value)
+		return err == nil && ok
+	}
+
 	switch sf.Op {
 	case Operation_OR:
 		if args := sf.GetFArgs(); args != nil {
@@ -41,12 +55,24 @@ func (sf SimpleFilter) Check(value string) bool {
 			return result
 		}
 		return true
+	case Operation_NOT:
+		if args := sf.GetFArgs(); args != nil {
+			for _, f := range args.Filters {
+				if !f.Check(value) {
+					return !f.typeMismatch(value, false)
+				}
+			}
+		}
+		return false
 	case Operation_NP:
 		return true
 	case Operation_EQ:
 		return value == sf.GetValue()
 	case Operation_NE:
 		return value != sf.GetValue()
+	case Operation_LIKE, Operation_REGEX, Operation_PREFIX, Operation_SUFFIX:
+		ok, err := matchString(sf.Op, value, sf.GetValue())
+		return err == nil && ok
 	}
 
 	var (
@@ -74,6 +100,217 @@ func (sf SimpleFilter) Check(value string) bool {
 	}
 }
 
+// checkFloat returns result of applying sf to numeric value v.
+// As in Check, non-parsable filter values are considered satisfied
+// only for String type.
+func (sf SimpleFilter) checkFloat(v float64) bool {
+	switch sf.Op {
+	case Operation_OR:
+		if args := sf.GetFArgs(); args != nil {
+			for _, f := range args.Filters {
+				if f.checkFloat(v) {
+					return true
+				}
+			}
+			return false
+		}
+		return true
+	case Operation_AND:
+		if args := sf.GetFArgs(); args != nil {
+			for _, f := range args.Filters {
+				if !f.checkFloat(v) {
+					return false
+				}
+			}
+		}
+		return true
+	case Operation_NOT:
+		if args := sf.GetFArgs(); args != nil {
+			for _, f := range args.Filters {
+				if !f.checkFloat(v) {
+					return !f.typeMismatch("", true)
+				}
+			}
+		}
+		return false
+	case Operation_NP:
+		return true
+	case Operation_LIKE, Operation_REGEX, Operation_PREFIX, Operation_SUFFIX:
+		ok, err := matchString(sf.Op, strconv.FormatFloat(v, 'f', -1, 64), sf.GetValue())
+		return err == nil && ok
+	}
+
+	exp, err := parseNumber(sf.GetValue(), sf.Type)
+	if err != nil {
+		return sf.Type == Type_String
+	}
+
+	switch sf.Op {
+	case Operation_EQ:
+		return v == exp
+	case Operation_NE:
+		return v != exp
+	case Operation_GT:
+		return v > exp
+	case Operation_GE:
+		return v >= exp
+	case Operation_LT:
+		return v < exp
+	case Operation_LE:
+		return v <= exp
+	default:
+		return true
+	}
+}
+
+// typeMismatch checks if some typed filter in sf can't be applied to value.
+// Typed filters never match values of other types, so the negation
+// of such filter must not match them too. If numeric is set, sf is applied
+// to numbers and only operands are checked.
+func (sf SimpleFilter) typeMismatch(value string, numeric bool) bool {
+	if args := sf.GetFArgs(); args != nil {
+		for _, f := range args.Filters {
+			if f.typeMismatch(value, numeric) {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch {
+	case sf.Type == Type_String:
+		return false
+	case numeric:
+		_, err := parseNumber(sf.GetValue(), sf.Type)
+		return err != nil
+	default:
+		_, err := sf.Match(value)
+		return err != nil
+	}
+}
+
+type (
+	patternKey struct {
+		op      Operation
+		operand string
+	}
+
+	compiledPattern struct {
+		re  *regexp.Regexp
+		err error
+	}
+)
+
+// maxPatterns is the maximum number of compiled patterns kept in cache.
+const maxPatterns = 256
+
+// patterns caches LIKE and REGEX operands compiled to regular expressions,
+// so that they are parsed once and not for every checked value.
+// Operands can come from user input, so the cache is bounded:
+// when it is full, some random entry is evicted.
+var patterns = struct {
+	sync.Mutex
+	m map[patternKey]compiledPattern
+}{m: make(map[patternKey]compiledPattern)}
+
+// compilePattern returns regular expression for LIKE or REGEX operand.
+func compilePattern(op Operation, operand string) (*regexp.Regexp, error) {
+	key := patternKey{op: op, operand: operand}
+
+	patterns.Lock()
+	p, ok := patterns.m[key]
+	patterns.Unlock()
+	if ok {
+		return p.re, p.err
+	}
+
+	if op == Operation_LIKE {
+		if p.re, p.err = globToRegexp(operand); p.err != nil {
+			p.err = errors.Errorf("invalid pattern %q", operand)
+		}
+	} else if p.re, p.err = regexp.Compile(operand); p.err != nil {
+		p.err = errors.Errorf("invalid regular expression %q", operand)
+	}
+
+	patterns.Lock()
+	if len(patterns.m) >= maxPatterns {
+		for k := range patterns.m {
+			delete(patterns.m, k)
+			break
+		}
+	}
+	patterns.m[key] = p
+	patterns.Unlock()
+	return p.re, p.err
+}
+
+// globToRegexp converts pattern in path.Match syntax to the equivalent
+// regular expression.
+func globToRegexp(pattern string) (*regexp.Regexp, error) {
+	// path.Match reports malformed patterns even if they don't match
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+
+	var (
+		sb      strings.Builder
+		rs      = []rune(pattern)
+		inClass bool
+		start   int
+	)
+
+	sb.WriteString("^")
+	for i := 0; i < len(rs); i++ {
+		switch r := rs[i]; {
+		case inClass && r == ']':
+			inClass = false
+			sb.WriteRune(r)
+		case inClass && (r == '-' || (r == '^' && i == start)):
+			sb.WriteRune(r)
+		case !inClass && r == '[':
+			inClass, start = true, i+1
+			sb.WriteRune(r)
+		case !inClass && r == '*':
+			sb.WriteString("[^/]*")
+		case !inClass && r == '?':
+			sb.WriteString("[^/]")
+		default:
+			if r == '\\' {
+				i++
+				r = rs[i]
+			}
+			// every literal is escaped, so it has no special meaning
+			fmt.Fprintf(&sb, `\x{%x}`, r)
+		}
+	}
+	sb.WriteString("$")
+	return regexp.Compile(sb.String())
+}
+
+// matchString applies string operation op to value with pattern operand.
+func matchString(op Operation, value, operand string) (bool, error) {
+	switch op {
+	case Operation_LIKE, Operation_REGEX:
+		re, err := compilePattern(op, operand)
+		if err != nil {
+			return false, err
+		}
+		return re.MatchString(value), nil
+	case Operation_PREFIX:
+		return strings.HasPrefix(value, operand), nil
+	case Operation_SUFFIX:
+		return strings.HasSuffix(value, operand), nil
+	default:
+		return false, errors.Errorf("%s is not a string operation", op)
+	}
+}
+
+// NodeFilterKey returns filter key matching node metric name.
+// Names "C" and "P" match node capacity and price.
+func NodeFilterKey(name string) string {
+	return NodeFilterPrefix + name
+}
+
 // Filter returns sublist of bs, satisfying f.
 func (f Filter) Filter(bs ...Bucket) []Bucket {
 	result := make([]Bucket, 0, len(bs))
@@ -93,6 +330,15 @@ func NewFilter(op Operation, value string) *SimpleFilter {
 	}
 }
 
+// NewTypedFilter constructs SimpleFilter comparing values as t.
+func NewTypedFilter(op Operation, t Type, value string) *SimpleFilter {
+	return &SimpleFilter{
+		Op:   op,
+		Args: &SimpleFilter_Value{Value: value},
+		Type: t,
+	}
+}
+
 // FilterIn returns filter, which checks if value is in specified list.
 func FilterIn(values ...string) *SimpleFilter {
 	fs := make([]*SimpleFilter, 0, len(values))
@@ -137,6 +383,47 @@ func FilterAND(fs ...*SimpleFilter) *SimpleFilter {
 	}
 }
 
+// FilterNOT returns filter, which checks if value doesn't satisfy f.
+func FilterNOT(f *SimpleFilter) *SimpleFilter {
+	return &SimpleFilter{
+		Op:   Operation_NOT,
+		Args: &SimpleFilter_FArgs{FArgs: &SimpleFilters{Filters: []SimpleFilter{*f}}},
+	}
+}
+
+// FilterLike returns filter, which checks if value matches glob pattern,
+// e.g. "San*". Pattern syntax is the same as in path.Match.
+func FilterLike(pattern string) *SimpleFilter {
+	return &SimpleFilter{
+		Op:   Operation_LIKE,
+		Args: &SimpleFilter_Value{Value: pattern},
+	}
+}
+
+// FilterRegex returns filter, which checks if value matches regular expression expr.
+func FilterRegex(expr string) *SimpleFilter {
+	return &SimpleFilter{
+		Op:   Operation_REGEX,
+		Args: &SimpleFilter_Value{Value: expr},
+	}
+}
+
+// FilterPrefix returns filter, which checks if value starts with p.
+func FilterPrefix(p string) *SimpleFilter {
+	return &SimpleFilter{
+		Op:   Operation_PREFIX,
+		Args: &SimpleFilter_Value{Value: p},
+	}
+}
+
+// FilterSuffix returns filter, which checks if value ends with s.
+func FilterSuffix(s string) *SimpleFilter {
+	return &SimpleFilter{
+		Op:   Operation_SUFFIX,
+		Args: &SimpleFilter_Value{Value: s},
+	}
+}
+
 // FilterEQ returns filter, which checks if value is equal to v.
 func FilterEQ(v string) *SimpleFilter {
 	return &SimpleFilter{
@@ -35,30 +35,48 @@ const (
 	Operation_LE  Operation = 6
 	Operation_OR  Operation = 7
 	Operation_AND Operation = 8
+	// LIKE matches value against glob pattern, e.g. "San*".
+	Operation_LIKE Operation = 9
+	// REGEX matches value against regular expression.
+	Operation_REGEX  Operation = 10
+	Operation_PREFIX Operation = 11
+	Operation_SUFFIX Operation = 12
+	// NOT negates conjunction of nested filters.
+	Operation_NOT Operation = 13
 )
 
 var Operation_name = map[int32]string{
-	0: "NP",
-	1: "EQ",
-	2: "NE",
-	3: "GT",
-	4: "GE",
-	5: "LT",
-	6: "LE",
-	7: "OR",
-	8: "AND",
+	0:  "NP",
+	1:  "EQ",
+	2:  "NE",
+	3:  "GT",
+	4:  "GE",
+	5:  "LT",
+	6:  "LE",
+	7:  "OR",
+	8:  "AND",
+	9:  "LIKE",
+	10: "REGEX",
+	11: "PREFIX",
+	12: "SUFFIX",
+	13: "NOT",
 }
 
 var Operation_value = map[string]int32{
-	"NP":  0,
-	"EQ":  1,
-	"NE":  2,
-	"GT":  3,
-	"GE":  4,
-	"LT":  5,
-	"LE":  6,
-	"OR":  7,
-	"AND": 8,
+	"NP":     0,
+	"EQ":     1,
+	"NE":     2,
+	"GT":     3,
+	"GE":     4,
+	"LT":     5,
+	"LE":     6,
+	"OR":     7,
+	"AND":    8,
+	"LIKE":   9,
+	"REGEX":  10,
+	"PREFIX": 11,
+	"SUFFIX": 12,
+	"NOT":    13,
 }
 
 func (x Operation) String() string {
@@ -74,16 +92,31 @@ type Type int32
 const (
 	Type_String  Type = 0
 	Type_Integer Type = 1
+	Type_Float   Type = 2
+	// Version is a semantic version, e.g. "v1.2.3-rc1".
+	Type_Version Type = 3
+	// Duration is a duration in Go format, e.g. "1h30m".
+	Type_Duration Type = 4
+	// Size is a size in bytes with optional unit, e.g. "10GB" or "1.5GiB".
+	Type_Size Type = 5
 )
 
 var Type_name = map[int32]string{
 	0: "String",
 	1: "Integer",
+	2: "Float",
+	3: "Version",
+	4: "Duration",
+	5: "Size",
 }
 
 var Type_value = map[string]int32{
-	"String":  0,
-	"Integer": 1,
+	"String":   0,
+	"Integer":  1,
+	"Float":    2,
+	"Version":  3,
+	"Duration": 4,
+	"Size":     5,
 }
 
 func (x Type) String() string {
@@ -150,9 +183,13 @@ func (m *PlacementRule) GetSFGroups() []SFGroup {
 }
 
 type SFGroup struct {
-	Filters              []Filter `protobuf:"bytes,1,rep,name=Filters,proto3" json:"Filters"`
-	Selectors            []Select `protobuf:"bytes,2,rep,name=Selectors,proto3" json:"Selectors"`
-	Exclude              []uint32 `protobuf:"varint,3,rep,packed,name=Exclude,proto3" json:"Exclude,omitempty"`
+	Filters   []Filter `protobuf:"bytes,1,rep,name=Filters,proto3" json:"Filters"`
+	Selectors []Select `protobuf:"bytes,2,rep,name=Selectors,proto3" json:"Selectors"`
+	Exclude   []uint32 `protobuf:"varint,3,rep,packed,name=Exclude,proto3" json:"Exclude,omitempty"`
+	// ExcludeBuckets contains paths to the buckets, which nodes are excluded,
+	// e.g. "/Location:Asia/Country:China". Path can start at any level.
+	ExcludeBuckets       []string `protobuf:"bytes,4,rep,name=ExcludeBuckets,proto3" json:"ExcludeBuckets,omitempty"`
+	Limits               []Limit  `protobuf:"bytes,5,rep,name=Limits,proto3" json:"Limits"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -212,6 +249,76 @@ func (m *SFGroup) GetExclude() []uint32 {
 	return nil
 }
 
+func (m *SFGroup) GetExcludeBuckets() []string {
+	if m != nil {
+		return m.ExcludeBuckets
+	}
+	return nil
+}
+
+func (m *SFGroup) GetLimits() []Limit {
+	if m != nil {
+		return m.Limits
+	}
+	return nil
+}
+
+// Limit restricts number of nodes selected from every bucket with Key to Max.
+type Limit struct {
+	Key                  string   `protobuf:"bytes,1,opt,name=Key,proto3" json:"Key,omitempty"`
+	Max                  uint32   `protobuf:"varint,2,opt,name=Max,proto3" json:"Max,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Limit) Reset()         { *m = Limit{} }
+func (m *Limit) String() string { return proto.CompactTextString(m) }
+func (*Limit) ProtoMessage()    {}
+func (*Limit) Descriptor() ([]byte, []int) {
+	return fileDescriptor_e4729c7385e2dd96, []int{2}
+}
+func (m *Limit) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *Limit) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_Limit.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *Limit) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Limit.Merge(m, src)
+}
+func (m *Limit) XXX_Size() int {
+	return m.Size()
+}
+func (m *Limit) XXX_DiscardUnknown() {
+	xxx_messageInfo_Limit.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Limit proto.InternalMessageInfo
+
+func (m *Limit) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *Limit) GetMax() uint32 {
+	if m != nil {
+		return m.Max
+	}
+	return 0
+}
+
 type Select struct {
 	Count                uint32   `protobuf:"varint,1,opt,name=Count,proto3" json:"Count,omitempty"`
 	Key                  string   `protobuf:"bytes,2,opt,name=Key,proto3" json:"Key,omitempty"`
@@ -224,7 +331,7 @@ func (m *Select) Reset()         { *m = Select{} }
 func (m *Select) String() string { return proto.CompactTextString(m) }
 func (*Select) ProtoMessage()    {}
 func (*Select) Descriptor() ([]byte, []int) {
-	return fileDescriptor_e4729c7385e2dd96, []int{2}
+	return fileDescriptor_e4729c7385e2dd96, []int{3}
 }
 func (m *Select) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -278,7 +385,7 @@ func (m *SimpleFilters) Reset()         { *m = SimpleFilters{} }
 func (m *SimpleFilters) String() string { return proto.CompactTextString(m) }
 func (*SimpleFilters) ProtoMessage()    {}
 func (*SimpleFilters) Descriptor() ([]byte, []int) {
-	return fileDescriptor_e4729c7385e2dd96, []int{3}
+	return fileDescriptor_e4729c7385e2dd96, []int{4}
 }
 func (m *SimpleFilters) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -320,6 +427,7 @@ type SimpleFilter struct {
 	//	*SimpleFilter_Value
 	//	*SimpleFilter_FArgs
 	Args                 isSimpleFilter_Args `protobuf_oneof:"Args"`
+	Type                 Type                `protobuf:"varint,4,opt,name=Type,proto3,enum=netmap.Type" json:"Type,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}            `json:"-"`
 	XXX_unrecognized     []byte              `json:"-"`
 	XXX_sizecache        int32               `json:"-"`
@@ -329,7 +437,7 @@ func (m *SimpleFilter) Reset()         { *m = SimpleFilter{} }
 func (m *SimpleFilter) String() string { return proto.CompactTextString(m) }
 func (*SimpleFilter) ProtoMessage()    {}
 func (*SimpleFilter) Descriptor() ([]byte, []int) {
-	return fileDescriptor_e4729c7385e2dd96, []int{4}
+	return fileDescriptor_e4729c7385e2dd96, []int{5}
 }
 func (m *SimpleFilter) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -402,6 +510,13 @@ func (m *SimpleFilter) GetFArgs() *SimpleFilters {
 	return nil
 }
 
+func (m *SimpleFilter) GetType() Type {
+	if m != nil {
+		return m.Type
+	}
+	return Type_String
+}
+
 // XXX_OneofWrappers is for the internal use of the proto package.
 func (*SimpleFilter) XXX_OneofWrappers() []interface{} {
 	return []interface{}{
@@ -422,7 +537,7 @@ func (m *Filter) Reset()         { *m = Filter{} }
 func (m *Filter) String() string { return proto.CompactTextString(m) }
 func (*Filter) ProtoMessage()    {}
 func (*Filter) Descriptor() ([]byte, []int) {
-	return fileDescriptor_e4729c7385e2dd96, []int{5}
+	return fileDescriptor_e4729c7385e2dd96, []int{6}
 }
 func (m *Filter) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -470,6 +585,7 @@ func init() {
 	proto.RegisterEnum("netmap.Type", Type_name, Type_value)
 	proto.RegisterType((*PlacementRule)(nil), "netmap.PlacementRule")
 	proto.RegisterType((*SFGroup)(nil), "netmap.SFGroup")
+	proto.RegisterType((*Limit)(nil), "netmap.Limit")
 	proto.RegisterType((*Select)(nil), "netmap.Select")
 	proto.RegisterType((*SimpleFilters)(nil), "netmap.SimpleFilters")
 	proto.RegisterType((*SimpleFilter)(nil), "netmap.SimpleFilter")
@@ -479,36 +595,45 @@ func init() {
 func init() { proto.RegisterFile("selector.proto", fileDescriptor_e4729c7385e2dd96) }
 
 var fileDescriptor_e4729c7385e2dd96 = []byte{
-	// 464 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x74, 0x52, 0xcd, 0x6e, 0xd3, 0x40,
-	0x18, 0xf4, 0xda, 0x89, 0xdd, 0x7c, 0x21, 0x61, 0x59, 0x15, 0x64, 0x71, 0x70, 0x8d, 0x4f, 0x56,
-	0xa5, 0xba, 0x10, 0x38, 0x23, 0x35, 0x60, 0x17, 0x04, 0x6a, 0xca, 0x26, 0xe2, 0xee, 0x84, 0xc5,
-	0x58, 0x72, 0xbc, 0x2b, 0x7b, 0x2d, 0xd1, 0x1b, 0x37, 0x5e, 0x81, 0x47, 0xea, 0x91, 0x27, 0x40,
-	0x28, 0xbc, 0x08, 0xf2, 0xfa, 0x87, 0x28, 0xa2, 0xa7, 0xf1, 0x7c, 0xdf, 0xcc, 0xce, 0x37, 0x92,
-	0x61, 0x5a, 0xb2, 0x8c, 0x6d, 0x24, 0x2f, 0x02, 0x51, 0x70, 0xc9, 0x89, 0x99, 0x33, 0xb9, 0x8d,
-	0xc5, 0xe3, 0xb3, 0x24, 0x95, 0x5f, 0xaa, 0x75, 0xb0, 0xe1, 0xdb, 0xf3, 0x84, 0x27, 0xfc, 0x5c,
-	0xad, 0xd7, 0xd5, 0x67, 0xc5, 0x14, 0x51, 0x5f, 0x8d, 0xcd, 0x5b, 0xc3, 0xe4, 0x3a, 0x8b, 0x37,
-	0x6c, 0xcb, 0x72, 0x49, 0xab, 0x8c, 0x11, 0x07, 0x80, 0x32, 0x91, 0x45, 0x71, 0xfd, 0xb6, 0x8d,
-	0x5c, 0xe4, 0x4f, 0xe8, 0xde, 0x84, 0x3c, 0x83, 0xa3, 0x65, 0x74, 0x59, 0xf0, 0x4a, 0x94, 0xb6,
-	0xee, 0x1a, 0xfe, 0x78, 0x76, 0x3f, 0x68, 0xa2, 0x83, 0x76, 0x3e, 0x1f, 0xdc, 0xfe, 0x3a, 0xd1,
-	0x68, 0x2f, 0xf3, 0xbe, 0x23, 0xb0, 0x5a, 0x42, 0x02, 0xb0, 0xa2, 0x34, 0x93, 0xac, 0x28, 0x6d,
-	0xa4, 0xdc, 0xd3, 0xce, 0xdd, 0x8c, 0x5b, 0x73, 0x27, 0x22, 0x33, 0x18, 0x2d, 0xdb, 0xa2, 0x5d,
-	0x5e, 0xef, 0x68, 0x16, 0xad, 0xe3, 0x9f, 0x8c, 0xd8, 0x60, 0x85, 0x5f, 0x37, 0x59, 0xf5, 0x89,
-	0xd9, 0x86, 0x6b, 0xf8, 0x13, 0xda, 0x51, 0xef, 0x29, 0x98, 0x8d, 0x8c, 0x1c, 0xc3, 0xf0, 0x15,
-	0xaf, 0x72, 0xd9, 0x36, 0x6c, 0x08, 0xc1, 0x60, 0xbc, 0x63, 0x37, 0xb6, 0xee, 0x22, 0x7f, 0x44,
-	0xeb, 0x4f, 0x2f, 0x84, 0xc9, 0x32, 0xdd, 0x8a, 0x8c, 0x75, 0x07, 0xbd, 0x38, 0x2c, 0x70, 0xdc,
-	0x9f, 0xb3, 0xa7, 0x3b, 0xa8, 0xe1, 0x7d, 0x43, 0x70, 0x6f, 0x7f, 0x4f, 0x9e, 0x80, 0xbe, 0x10,
-	0x2a, 0x7c, 0x3a, 0x7b, 0xd0, 0xbd, 0xb0, 0x10, 0xac, 0x88, 0x65, 0xca, 0x73, 0xaa, 0x2f, 0x04,
-	0x79, 0x04, 0xc3, 0x8f, 0x71, 0x56, 0xb1, 0xe6, 0x9c, 0x37, 0x1a, 0x6d, 0x28, 0x39, 0x83, 0x61,
-	0x74, 0x51, 0x24, 0xa5, 0x6d, 0xb8, 0xc8, 0x1f, 0xcf, 0x1e, 0xfe, 0x2f, 0xbf, 0xac, 0xe5, 0x4a,
-	0x35, 0x37, 0x61, 0x50, 0xa3, 0xf7, 0x12, 0xcc, 0x36, 0xbb, 0x6d, 0x89, 0xfa, 0x96, 0xc4, 0x03,
-	0x14, 0xa9, 0x98, 0x3b, 0xea, 0x50, 0x14, 0x9d, 0xae, 0x60, 0xd4, 0xdf, 0x47, 0x4c, 0xd0, 0xaf,
-	0xae, 0xb1, 0x56, 0x63, 0xf8, 0x01, 0x23, 0xc5, 0x43, 0xac, 0xd7, 0x78, 0xb9, 0xc2, 0x86, 0xc2,
-	0x10, 0x0f, 0x6a, 0x7c, 0xbf, 0xc2, 0x43, 0x85, 0x21, 0x36, 0x6b, 0x5c, 0x50, 0x6c, 0x11, 0x0b,
-	0x8c, 0x8b, 0xab, 0xd7, 0xf8, 0xe8, 0xf4, 0x04, 0x06, 0xab, 0x1b, 0xc1, 0x08, 0x80, 0xb9, 0x94,
-	0x45, 0x9a, 0x27, 0x58, 0x23, 0x63, 0xb0, 0xde, 0xe6, 0x92, 0x25, 0xac, 0xc0, 0x68, 0x8e, 0x6f,
-	0x77, 0x0e, 0xfa, 0xb9, 0x73, 0xd0, 0xef, 0x9d, 0x83, 0x7e, 0xfc, 0x71, 0xb4, 0xb5, 0xa9, 0xfe,
-	0xdc, 0xe7, 0x7f, 0x03, 0x00, 0x00, 0xff, 0xff, 0xe7, 0x59, 0x33, 0x15, 0x02, 0x03, 0x00, 0x00,
+	// 597 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x74, 0x53, 0xcd, 0x6e, 0xd3, 0x40,
+	0x10, 0xce, 0xfa, 0x2f, 0xc9, 0x24, 0x0e, 0xcb, 0xaa, 0x20, 0x8b, 0x43, 0x30, 0x3e, 0xa0, 0xa8,
+	0x55, 0x53, 0x08, 0x9c, 0x91, 0x1a, 0x6a, 0x97, 0xaa, 0xa5, 0x2e, 0x9b, 0x50, 0xf5, 0xea, 0x84,
+	0xc5, 0x58, 0x38, 0xb6, 0x65, 0xaf, 0xa5, 0x96, 0x47, 0xe0, 0x09, 0x38, 0xf3, 0x34, 0x3d, 0xf2,
+	0x00, 0x08, 0xa1, 0xf2, 0x22, 0x68, 0xd7, 0x76, 0xa8, 0x02, 0x9c, 0x66, 0xe6, 0x9b, 0x6f, 0xe6,
+	0xdb, 0xf9, 0xa4, 0x85, 0x41, 0xc1, 0x62, 0xb6, 0xe4, 0x69, 0x3e, 0xce, 0xf2, 0x94, 0xa7, 0xc4,
+	0x48, 0x18, 0x5f, 0x05, 0xd9, 0x83, 0xdd, 0x30, 0xe2, 0x1f, 0xca, 0xc5, 0x78, 0x99, 0xae, 0xf6,
+	0xc2, 0x34, 0x4c, 0xf7, 0x64, 0x7b, 0x51, 0xbe, 0x97, 0x95, 0x2c, 0x64, 0x56, 0x8d, 0x39, 0x0b,
+	0x30, 0xcf, 0xe2, 0x60, 0xc9, 0x56, 0x2c, 0xe1, 0xb4, 0x8c, 0x19, 0x19, 0x02, 0x50, 0x96, 0xc5,
+	0x5e, 0x20, 0x76, 0x5b, 0xc8, 0x46, 0x23, 0x93, 0xde, 0x42, 0xc8, 0x53, 0xe8, 0xcc, 0xbc, 0xc3,
+	0x3c, 0x2d, 0xb3, 0xc2, 0x52, 0x6c, 0x75, 0xd4, 0x9b, 0xdc, 0x19, 0x57, 0xd2, 0xe3, 0x1a, 0x9f,
+	0x6a, 0xd7, 0x3f, 0x1e, 0xb6, 0xe8, 0x9a, 0xe6, 0x7c, 0x47, 0xd0, 0xae, 0x0b, 0x32, 0x86, 0xb6,
+	0x17, 0xc5, 0x9c, 0xe5, 0x85, 0x85, 0xe4, 0xf4, 0xa0, 0x99, 0xae, 0xe0, 0x7a, 0xb8, 0x21, 0x91,
+	0x09, 0x74, 0x67, 0xf5, 0xa1, 0x8d, 0xde, 0x7a, 0xa2, 0x6a, 0xd4, 0x13, 0x7f, 0x68, 0xc4, 0x82,
+	0xb6, 0x7b, 0xb9, 0x8c, 0xcb, 0x77, 0xcc, 0x52, 0x6d, 0x75, 0x64, 0xd2, 0xa6, 0x24, 0x8f, 0x61,
+	0x50, 0xa7, 0xd3, 0x72, 0xf9, 0x91, 0xf1, 0xc2, 0xd2, 0x6c, 0x75, 0xd4, 0xa5, 0x1b, 0x28, 0xd9,
+	0x01, 0xe3, 0x24, 0x5a, 0x45, 0xbc, 0xb0, 0x74, 0x29, 0x69, 0x36, 0x92, 0x12, 0xad, 0x15, 0x6b,
+	0x8a, 0xb3, 0x03, 0xba, 0xcc, 0x08, 0x06, 0xf5, 0x98, 0x5d, 0x49, 0xcf, 0xba, 0x54, 0xa4, 0x02,
+	0x79, 0x1d, 0x5c, 0x5a, 0x8a, 0x74, 0x51, 0xa4, 0xce, 0x13, 0x30, 0xaa, 0x87, 0x92, 0x2d, 0xd0,
+	0x5f, 0xa6, 0x65, 0xc2, 0x6b, 0x8f, 0xab, 0xa2, 0xd9, 0xa1, 0xac, 0x77, 0x38, 0x2e, 0x98, 0xb3,
+	0x68, 0x95, 0xc5, 0xac, 0xb1, 0xe4, 0xf9, 0xa6, 0x85, 0x5b, 0x6b, 0x43, 0x6e, 0xf1, 0x36, 0x8c,
+	0x74, 0xbe, 0x22, 0xe8, 0xdf, 0xee, 0x93, 0x47, 0xa0, 0xf8, 0x99, 0x14, 0x1f, 0x4c, 0xee, 0x36,
+	0x1b, 0xfc, 0x8c, 0xe5, 0x01, 0x8f, 0xd2, 0x84, 0x2a, 0x7e, 0x46, 0xee, 0x83, 0x7e, 0x1e, 0xc4,
+	0x25, 0xab, 0x9e, 0xf3, 0xaa, 0x45, 0xab, 0x92, 0xec, 0x82, 0xee, 0xed, 0xe7, 0x61, 0x61, 0xa9,
+	0x36, 0x1a, 0xf5, 0x26, 0xf7, 0xfe, 0xa5, 0x5f, 0x08, 0xba, 0x64, 0x11, 0x1b, 0xb4, 0xf9, 0x55,
+	0xc6, 0x2c, 0x4d, 0x6a, 0xf5, 0x1b, 0xb6, 0xc0, 0xa8, 0xec, 0x4c, 0x0d, 0xd0, 0x04, 0xd3, 0x79,
+	0x01, 0x46, 0xfd, 0xba, 0xbf, 0xbd, 0x74, 0x00, 0x79, 0xf2, 0x21, 0xff, 0x39, 0x98, 0x22, 0x6f,
+	0xfb, 0x33, 0x82, 0xee, 0xfa, 0x04, 0x62, 0x80, 0x72, 0x7a, 0x86, 0x5b, 0x22, 0xba, 0x6f, 0x30,
+	0x92, 0xb5, 0x8b, 0x15, 0x11, 0x0f, 0xe7, 0x58, 0x95, 0xd1, 0xc5, 0x9a, 0x88, 0x27, 0x73, 0xac,
+	0xcb, 0xe8, 0x62, 0x43, 0x44, 0x9f, 0xe2, 0x36, 0x69, 0x83, 0xba, 0x7f, 0x7a, 0x80, 0x3b, 0xa4,
+	0x03, 0xda, 0xc9, 0xd1, 0xb1, 0x8b, 0xbb, 0xa4, 0x0b, 0x3a, 0x75, 0x0f, 0xdd, 0x0b, 0x0c, 0x04,
+	0xc0, 0x38, 0xa3, 0xae, 0x77, 0x74, 0x81, 0x7b, 0x22, 0x9f, 0xbd, 0xf5, 0x44, 0xde, 0x17, 0x53,
+	0xa7, 0xfe, 0x1c, 0x9b, 0xdb, 0x7e, 0x75, 0xb6, 0x6c, 0xf2, 0x3c, 0x4a, 0x42, 0xdc, 0x22, 0x3d,
+	0x68, 0x1f, 0x25, 0x9c, 0x85, 0x2c, 0xc7, 0x48, 0x2c, 0xf3, 0xe2, 0x34, 0xe0, 0x58, 0x11, 0xf8,
+	0x39, 0xcb, 0x8b, 0x28, 0x4d, 0xb0, 0x4a, 0xfa, 0xd0, 0x39, 0x28, 0xab, 0x1b, 0xb0, 0x26, 0xc4,
+	0x67, 0xd1, 0x27, 0x86, 0xf5, 0x29, 0xbe, 0xbe, 0x19, 0xa2, 0x6f, 0x37, 0x43, 0xf4, 0xf3, 0x66,
+	0x88, 0xbe, 0xfc, 0x1a, 0xb6, 0x16, 0x86, 0xfc, 0xc4, 0xcf, 0x7e, 0x0f, 0x00, 0xc5, 0xe4, 0x38,
+	0x49, 0x0d, 0x04, 0x00, 0x00,
 }
 
 func (m *PlacementRule) Marshal() (dAtA []byte, err error) {
@@ -581,6 +706,29 @@ func (m *SFGroup) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		i -= len(m.XXX_unrecognized)
 		copy(dAtA[i:], m.XXX_unrecognized)
 	}
+	if len(m.Limits) > 0 {
+		for iNdEx := len(m.Limits) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Limits[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintSelector(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x2a
+		}
+	}
+	if len(m.ExcludeBuckets) > 0 {
+		for iNdEx := len(m.ExcludeBuckets) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.ExcludeBuckets[iNdEx])
+			copy(dAtA[i:], m.ExcludeBuckets[iNdEx])
+			i = encodeVarintSelector(dAtA, i, uint64(len(m.ExcludeBuckets[iNdEx])))
+			i--
+			dAtA[i] = 0x22
+		}
+	}
 	if len(m.Exclude) > 0 {
 		dAtA2 := make([]byte, len(m.Exclude)*10)
 		var j1 int
@@ -630,6 +778,45 @@ func (m *SFGroup) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	return len(dAtA) - i, nil
 }
 
+func (m *Limit) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Limit) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *Limit) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if m.Max != 0 {
+		i = encodeVarintSelector(dAtA, i, uint64(m.Max))
+		i--
+		dAtA[i] = 0x10
+	}
+	if len(m.Key) > 0 {
+		i -= len(m.Key)
+		copy(dAtA[i:], m.Key)
+		i = encodeVarintSelector(dAtA, i, uint64(len(m.Key)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
 func (m *Select) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
@@ -734,6 +921,11 @@ func (m *SimpleFilter) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		i -= len(m.XXX_unrecognized)
 		copy(dAtA[i:], m.XXX_unrecognized)
 	}
+	if m.Type != 0 {
+		i = encodeVarintSelector(dAtA, i, uint64(m.Type))
+		i--
+		dAtA[i] = 0x20
+	}
 	if m.Args != nil {
 		{
 			size := m.Args.Size()
@@ -889,6 +1081,37 @@ func (m *SFGroup) Size() (n int) {
 		}
 		n += 1 + sovSelector(uint64(l)) + l
 	}
+	if len(m.ExcludeBuckets) > 0 {
+		for _, s := range m.ExcludeBuckets {
+			l = len(s)
+			n += 1 + l + sovSelector(uint64(l))
+		}
+	}
+	if len(m.Limits) > 0 {
+		for _, e := range m.Limits {
+			l = e.Size()
+			n += 1 + l + sovSelector(uint64(l))
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *Limit) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Key)
+	if l > 0 {
+		n += 1 + l + sovSelector(uint64(l))
+	}
+	if m.Max != 0 {
+		n += 1 + sovSelector(uint64(m.Max))
+	}
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
@@ -944,6 +1167,9 @@ func (m *SimpleFilter) Size() (n int) {
 	if m.Args != nil {
 		n += m.Args.Size()
 	}
+	if m.Type != 0 {
+		n += 1 + sovSelector(uint64(m.Type))
+	}
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
@@ -1278,6 +1504,177 @@ func (m *SFGroup) Unmarshal(dAtA []byte) error {
 			} else {
 				return fmt.Errorf("proto: wrong wireType = %d for field Exclude", wireType)
 			}
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ExcludeBuckets", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowSelector
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthSelector
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthSelector
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.ExcludeBuckets = append(m.ExcludeBuckets, string(dAtA[iNdEx:postIndex]))
+			iNdEx = postIndex
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Limits", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowSelector
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthSelector
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthSelector
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Limits = append(m.Limits, Limit{})
+			if err := m.Limits[len(m.Limits)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipSelector(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthSelector
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthSelector
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Limit) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowSelector
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Limit: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Limit: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Key", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowSelector
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthSelector
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthSelector
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Key = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Max", wireType)
+			}
+			m.Max = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowSelector
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Max |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipSelector(dAtA[iNdEx:])
@@ -1611,6 +2008,25 @@ func (m *SimpleFilter) Unmarshal(dAtA []byte) error {
 			}
 			m.Args = &SimpleFilter_FArgs{v}
 			iNdEx = postIndex
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Type", wireType)
+			}
+			m.Type = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowSelector
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Type |= Type(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipSelector(dAtA[iNdEx:])
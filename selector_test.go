@@ -1,6 +1,8 @@
 package netmap
 
 import (
+	"path"
+	"strconv"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -105,3 +107,200 @@ func TestFilterLE(t *testing.T) {
 	require.False(t, f.Check("0"))
 	require.True(t, f.Check("nan"))
 }
+
+func TestSimpleFilter_Match(t *testing.T) {
+	cases := []struct {
+		f     *SimpleFilter
+		value string
+		ok    bool
+	}{
+		{NewTypedFilter(Operation_GT, Type_Integer, "10"), "11", true},
+		{NewTypedFilter(Operation_GT, Type_Integer, "10"), "9", false},
+		{NewTypedFilter(Operation_EQ, Type_Integer, "10"), "010", true},
+		{NewTypedFilter(Operation_LE, Type_Float, "0.5"), "0.25", true},
+		{NewTypedFilter(Operation_LE, Type_Float, "0.5"), "1e3", false},
+		{NewTypedFilter(Operation_GE, Type_Version, "v1.2"), "1.2.0", true},
+		{NewTypedFilter(Operation_GE, Type_Version, "v1.10.0"), "v1.9.9", false},
+		{NewTypedFilter(Operation_LT, Type_Version, "1.0.0"), "1.0.0-rc.1", true},
+		{NewTypedFilter(Operation_LT, Type_Version, "1.0.0-rc.2"), "1.0.0-rc.10", false},
+		{NewTypedFilter(Operation_LT, Type_Version, "1.0.0-beta"), "1.0.0-alpha.1", true},
+		{NewTypedFilter(Operation_EQ, Type_Version, "1.0.0"), "v1.0.0+build5", true},
+		{NewTypedFilter(Operation_GE, Type_Duration, "1h"), "90m", true},
+		{NewTypedFilter(Operation_GE, Type_Duration, "1h"), "59m59s", false},
+		{NewTypedFilter(Operation_GE, Type_Size, "10GB"), "10000000000", true},
+		{NewTypedFilter(Operation_GE, Type_Size, "10GB"), "9.5GB", false},
+		{NewTypedFilter(Operation_GT, Type_Size, "1GB"), "1GiB", true},
+		{NewTypedFilter(Operation_EQ, Type_Size, "1kb"), "1000B", true},
+		{NewTypedFilter(Operation_GT, Type_String, "9"), "10", true},
+		{NewTypedFilter(Operation_NE, Type_String, "abc"), "abd", true},
+		{FilterOR(NewTypedFilter(Operation_LT, Type_Size, "1MB"), NewTypedFilter(Operation_GT, Type_Size, "1TB")), "2TB", true},
+		{FilterAND(NewTypedFilter(Operation_GT, Type_Size, "1MB"), NewTypedFilter(Operation_LT, Type_Size, "1TB")), "2TB", false},
+	}
+
+	for _, c := range cases {
+		ok, err := c.f.Match(c.value)
+		require.NoError(t, err, "%s %s", c.f, c.value)
+		require.Equal(t, c.ok, ok, "%s %s", c.f, c.value)
+		require.Equal(t, c.ok, c.f.Check(c.value), "%s %s", c.f, c.value)
+	}
+
+	t.Run("mismatched types", func(t *testing.T) {
+		errCases := []struct {
+			f     *SimpleFilter
+			value string
+			err   string
+		}{
+			{NewTypedFilter(Operation_GT, Type_Integer, "10"), "1.5", `can't parse "1.5" as integer`},
+			{NewTypedFilter(Operation_GT, Type_Integer, "ten"), "1", `can't parse "ten" as integer`},
+			{NewTypedFilter(Operation_GT, Type_Float, "1"), "abc", `can't parse "abc" as float`},
+			{NewTypedFilter(Operation_GT, Type_Version, "1.0"), "1.x", `can't parse "1.x" as version`},
+			{NewTypedFilter(Operation_GT, Type_Version, "1.0"), "1.0.0.0", `can't parse "1.0.0.0" as version`},
+			{NewTypedFilter(Operation_GT, Type_Duration, "1h"), "1 day", `can't parse "1 day" as duration`},
+			{NewTypedFilter(Operation_GT, Type_Size, "1GB"), "1XB", `can't parse "1XB" as size: unknown unit "XB"`},
+			{NewTypedFilter(Operation_GT, Type_String, "10"), "nan", `can't parse "nan" as integer`},
+			{NewTypedFilter(Operation(42), Type_Integer, "10"), "1", "unknown operation 42"},
+			{&SimpleFilter{Op: Operation_OR}, "1", "OR without arguments"},
+		}
+
+		for _, c := range errCases {
+			_, err := c.f.Match(c.value)
+			require.EqualError(t, err, c.err)
+		}
+
+		// typed filters never match values of other types
+		require.False(t, NewTypedFilter(Operation_NE, Type_Integer, "10").Check("abc"))
+		// untyped filters keep matching them
+		require.True(t, FilterGT(10).Check("abc"))
+	})
+}
+
+func TestFilterLike(t *testing.T) {
+	f := FilterLike("San*")
+	require.True(t, f.Check("San Francisco"))
+	require.True(t, f.Check("San"))
+	require.False(t, f.Check("Los Angeles"))
+	require.False(t, f.Check("san Jose"))
+
+	f = FilterLike("[")
+	require.False(t, f.Check("["))
+	_, err := f.Match("[")
+	require.EqualError(t, err, `invalid pattern "["`)
+	require.Error(t, f.Validate())
+}
+
+func TestGlobToRegexp(t *testing.T) {
+	values := []string{"", "a", "abc", "a/b", "San Jose", "a*b", "a-b", "[x]", "^", "b", "ä", "a?c", `a\c`}
+	for _, pattern := range []string{
+		"*", "a*", "?", "a?c", "*/*", "[a-c]", "[^a-c]", "[^/]", `\*`, `a\?c`,
+		"[[^]", `[\-]`, "[ä-ö]", "San*", `\[*\]`, "^", "a-b", "[a-]b",
+	} {
+		if _, err := path.Match(pattern, ""); err != nil {
+			_, err = globToRegexp(pattern)
+			require.Error(t, err, pattern)
+			continue
+		}
+
+		re, err := globToRegexp(pattern)
+		require.NoError(t, err, pattern)
+		for _, v := range values {
+			expected, _ := path.Match(pattern, v)
+			require.Equal(t, expected, re.MatchString(v), "pattern %q, value %q", pattern, v)
+		}
+	}
+}
+
+func TestCompilePattern(t *testing.T) {
+	re, err := compilePattern(Operation_REGEX, "^B.*n$")
+	require.NoError(t, err)
+
+	again, err := compilePattern(Operation_REGEX, "^B.*n$")
+	require.NoError(t, err)
+	require.True(t, re == again, "compiled pattern must be cached")
+
+	_, err = compilePattern(Operation_LIKE, "^B.*n$")
+	require.NoError(t, err)
+	like, err := compilePattern(Operation_LIKE, "^B.*n$")
+	require.NoError(t, err)
+	require.False(t, re == like, "LIKE and REGEX operands are cached separately")
+
+	for i := 0; i < 2*maxPatterns; i++ {
+		_, err = compilePattern(Operation_REGEX, strconv.Itoa(i))
+		require.NoError(t, err)
+		_, err = compilePattern(Operation_REGEX, "("+strconv.Itoa(i))
+		require.Error(t, err)
+	}
+	require.Len(t, patterns.m, maxPatterns)
+}
+
+func TestFilterRegex(t *testing.T) {
+	f := FilterRegex("^(Berlin|Bonn)$")
+	require.True(t, f.Check("Berlin"))
+	require.True(t, f.Check("Bonn"))
+	require.False(t, f.Check("Bern"))
+
+	f = FilterRegex("(")
+	require.False(t, f.Check("("))
+	require.EqualError(t, f.Validate(), `invalid regular expression "("`)
+}
+
+func TestFilterPrefixSuffix(t *testing.T) {
+	f := FilterPrefix("eu-")
+	require.True(t, f.Check("eu-west"))
+	require.False(t, f.Check("us-east"))
+
+	f = FilterSuffix("-west")
+	require.True(t, f.Check("eu-west"))
+	require.False(t, f.Check("us-east"))
+
+	// string operations ignore type
+	f = NewTypedFilter(Operation_PREFIX, Type_Integer, "1")
+	ok, err := f.Match("10")
+	require.NoError(t, err)
+	require.True(t, ok)
+}
+
+func TestFilterNOT(t *testing.T) {
+	f := FilterNOT(FilterIn("Moscow", "Beijing"))
+	require.True(t, f.Check("Berlin"))
+	require.False(t, f.Check("Moscow"))
+
+	f = FilterNOT(FilterLike("San*"))
+	require.False(t, f.Check("San Jose"))
+	require.True(t, f.Check("Los Angeles"))
+
+	f = FilterNOT(FilterNOT(FilterGT(10)))
+	require.True(t, f.Check("11"))
+	require.False(t, f.Check("9"))
+
+	f = FilterNOT(NewTypedFilter(Operation_GT, Type_Size, "1GB"))
+	ok, err := f.Match("1MB")
+	require.NoError(t, err)
+	require.True(t, ok)
+	_, err = f.Match("1XB")
+	require.Error(t, err)
+
+	// typed filters and their negations never match values of other types
+	require.False(t, NewTypedFilter(Operation_GT, Type_Size, "1GB").Check("big"))
+	require.False(t, f.Check("big"))
+	require.True(t, f.Check("1MB"))
+	require.False(t, FilterNOT(FilterOR(FilterEQ("small"), NewTypedFilter(Operation_GT, Type_Size, "1GB"))).Check("big"))
+	require.False(t, FilterNOT(NewTypedFilter(Operation_GT, Type_Version, "1.0")).checkFloat(1))
+	require.True(t, FilterNOT(NewTypedFilter(Operation_GT, Type_Size, "1KB")).checkFloat(1))
+
+	f = &SimpleFilter{Op: Operation_NOT}
+	require.EqualError(t, f.Validate(), "NOT without arguments")
+
+	root, err := newRoot(
+		bucket{"/Location:America/City:San Francisco", []uint32{1}},
+		bucket{"/Location:America/City:San Jose", []uint32{2}},
+		bucket{"/Location:America/City:Los Angeles", []uint32{3}},
+	)
+	require.NoError(t, err)
+
+	s := SFGroup{
+		Filters:   []Filter{{Key: "City", F: FilterNOT(FilterLike("San*"))}},
+		Selectors: []Select{{Key: NodesBucket, Count: 1}},
+	}
+	require.Equal(t, []uint32{3}, root.GetMaxSelection(s).Nodelist().Nodes())
+	require.NoError(t, s.Validate())
+}
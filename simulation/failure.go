@@ -0,0 +1,149 @@
+package simulation
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/nspcc-dev/netmap"
+)
+
+type (
+	// Scenario describes a set of simultaneously failed nodes.
+	Scenario struct {
+		Name   string
+		Failed map[uint32]bool
+	}
+
+	// Survival contains results of a failure scenario simulation.
+	Survival struct {
+		Scenario string
+		// Pivots is the number of simulated objects.
+		Pivots int
+		// Lost is the number of objects which lost all replicas.
+		Lost int
+		// Degraded is the number of objects with less alive replicas
+		// than threshold. Lost objects are also counted.
+		Degraded int
+	}
+)
+
+// NodeFailure returns scenario in which nodes ns are down.
+func NodeFailure(ns ...uint32) Scenario {
+	var (
+		s     = Scenario{Failed: make(map[uint32]bool, len(ns))}
+		names = make([]string, 0, len(ns))
+	)
+
+	for _, n := range ns {
+		s.Failed[n] = true
+		names = append(names, strconv.FormatUint(uint64(n), 10))
+	}
+	s.Name = "nodes " + strings.Join(names, ",")
+	return s
+}
+
+// DomainFailures returns scenarios for every combination
+// of count buckets with the specified key being down.
+func DomainFailures(b netmap.Bucket, key string, count int) []Scenario {
+	var (
+		paths   []string
+		domains []netmap.Nodes
+		ss      []Scenario
+	)
+
+	collectDomains("", b, key, &paths, &domains)
+	if count <= 0 || count > len(domains) {
+		return nil
+	}
+
+	idx := make([]int, count)
+	for i := range idx {
+		idx[i] = i
+	}
+	for {
+		s := Scenario{Failed: make(map[uint32]bool)}
+		names := make([]string, 0, count)
+		for _, i := range idx {
+			names = append(names, paths[i])
+			for _, n := range domains[i] {
+				s.Failed[n.N] = true
+			}
+		}
+		s.Name = strings.Join(names, ",")
+		ss = append(ss, s)
+
+		// advance to the next combination
+		i := count - 1
+		for i >= 0 && idx[i] == len(domains)-count+i {
+			i--
+		}
+		if i < 0 {
+			return ss
+		}
+		idx[i]++
+		for j := i + 1; j < count; j++ {
+			idx[j] = idx[j-1] + 1
+		}
+	}
+}
+
+func collectDomains(prefix string, b netmap.Bucket, key string, paths *[]string, domains *[]netmap.Nodes) {
+	for _, c := range b.Children() {
+		path := prefix + netmap.Separator + c.Name()
+		if c.Key == key {
+			*paths = append(*paths, path)
+			*domains = append(*domains, c.Nodelist())
+			continue
+		}
+		collectDomains(path, c, key, paths, domains)
+	}
+}
+
+// Survive places every pivot in b according to placement rule ss
+// and checks how many replicas survive in each scenario.
+func Survive(b netmap.Bucket, pivots [][]byte, threshold int, scenarios []Scenario, ss ...netmap.SFGroup) []Survival {
+	var (
+		placements = make([]netmap.Nodes, 0, len(pivots))
+		result     = make([]Survival, 0, len(scenarios))
+	)
+
+	for _, pivot := range pivots {
+		placements = append(placements, b.FindNodes(pivot, ss...))
+	}
+
+	for _, s := range scenarios {
+		r := Survival{Scenario: s.Name, Pivots: len(pivots)}
+		for _, nodes := range placements {
+			alive := 0
+			for _, n := range nodes {
+				if !s.Failed[n.N] {
+					alive++
+				}
+			}
+			if alive == 0 {
+				r.Lost++
+			}
+			if alive < threshold {
+				r.Degraded++
+			}
+		}
+		result = append(result, r)
+	}
+	return result
+}
+
+// LostFraction returns fraction of objects which lost all replicas.
+func (s Survival) LostFraction() float64 {
+	if s.Pivots == 0 {
+		return 0
+	}
+	return float64(s.Lost) / float64(s.Pivots)
+}
+
+// DegradedFraction returns fraction of objects with less replicas than threshold.
+func (s Survival) DegradedFraction() float64 {
+	if s.Pivots == 0 {
+		return 0
+	}
+	return float64(s.Degraded) / float64(s.Pivots)
+}
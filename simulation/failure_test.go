@@ -0,0 +1,71 @@
+package simulation
+
+import (
+	"testing"
+
+	"github.com/nspcc-dev/netmap"
+	"github.com/stretchr/testify/require"
+)
+
+func newFailureBucket(t *testing.T) (b netmap.Bucket) {
+	require.NoError(t, b.AddBucket("/Location:Europe/Country:Germany", netmap.Nodes{{N: 1}, {N: 2}}))
+	require.NoError(t, b.AddBucket("/Location:Europe/Country:Austria", netmap.Nodes{{N: 3}, {N: 4}}))
+	require.NoError(t, b.AddBucket("/Location:Asia/Country:Japan", netmap.Nodes{{N: 5}, {N: 6}}))
+	return
+}
+
+func TestDomainFailures(t *testing.T) {
+	b := newFailureBucket(t)
+
+	ss := DomainFailures(b, "Country", 1)
+	require.Len(t, ss, 3)
+	require.Equal(t, "/Location:Europe/Country:Germany", ss[0].Name)
+	require.Equal(t, map[uint32]bool{1: true, 2: true}, ss[0].Failed)
+
+	ss = DomainFailures(b, "Country", 2)
+	require.Len(t, ss, 3)
+	require.Equal(t, "/Location:Europe/Country:Germany,/Location:Asia/Country:Japan", ss[1].Name)
+	require.Equal(t, map[uint32]bool{1: true, 2: true, 5: true, 6: true}, ss[1].Failed)
+
+	ss = DomainFailures(b, "Location", 2)
+	require.Len(t, ss, 1)
+
+	require.Nil(t, DomainFailures(b, "Location", 3))
+	require.Nil(t, DomainFailures(b, "City", 1))
+
+	s := NodeFailure(1, 3)
+	require.Equal(t, "nodes 1,3", s.Name)
+	require.Equal(t, map[uint32]bool{1: true, 3: true}, s.Failed)
+}
+
+func TestSurvive(t *testing.T) {
+	var (
+		b      = newFailureBucket(t)
+		pivots = netmap.SamplePivots(500)
+		ss     = netmap.SFGroup{Selectors: []netmap.Select{
+			{Key: "Country", Count: 2},
+			{Key: netmap.NodesBucket, Count: 1},
+		}}
+	)
+
+	// select 2 Country survives any single country outage
+	rs := Survive(b, pivots, 2, DomainFailures(b, "Country", 1), ss)
+	require.Len(t, rs, 3)
+	for _, r := range rs {
+		require.Equal(t, 500, r.Pivots)
+		require.Equal(t, 0, r.Lost)
+		require.Equal(t, 0.0, r.LostFraction())
+		require.True(t, r.Degraded > 0)
+	}
+
+	// but not outage of Europe
+	rs = Survive(b, pivots, 2, DomainFailures(b, "Location", 1), ss)
+	require.Len(t, rs, 2)
+	require.True(t, rs[0].LostFraction() > 0)
+	require.Equal(t, 500, rs[0].Degraded)
+	require.Equal(t, 0, rs[1].Lost)
+
+	rs = Survive(b, pivots, 1, []Scenario{NodeFailure(1, 2, 3, 4, 5, 6)}, ss)
+	require.Equal(t, 500, rs[0].Lost)
+	require.Equal(t, 1.0, rs[0].DegradedFraction())
+}
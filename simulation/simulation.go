@@ -0,0 +1,150 @@
+// Package simulation estimates load distribution of placement rules
+// over a netmap.
+package simulation
+
+import (
+	"math"
+	"sort"
+
+	"github.com/nspcc-dev/netmap"
+)
+
+type (
+	// Report contains results of a simulation.
+	Report struct {
+		// Pivots is the number of simulated placements.
+		Pivots int
+		// Nodes maps node index to its hits.
+		Nodes map[uint32]Hits
+		// Buckets maps full bucket path to hits of all its nodes.
+		Buckets map[string]Hits
+		// Hits describes spread of actual node hits.
+		Hits Stats
+		// Load describes spread of node load, i.e. ratio
+		// of actual hits to expected ones.
+		Load Stats
+	}
+
+	// Hits contains actual and expected number of placements.
+	// Expected number is proportional to the node weight.
+	Hits struct {
+		Actual   int
+		Expected float64
+	}
+
+	// Stats contains fairness metrics of a distribution.
+	Stats struct {
+		Mean    float64
+		StdDev  float64
+		MaxMean float64
+		Gini    float64
+	}
+)
+
+// Run places every pivot in b according to placement rule ss
+// and collects per-node and per-bucket statistics.
+func Run(b netmap.Bucket, pivots [][]byte, ss ...netmap.SFGroup) Report {
+	var (
+		nodes   = b.Nodelist()
+		weights = nodes.Weights()
+		total   int
+		sum     float64
+		r       = Report{
+			Pivots:  len(pivots),
+			Nodes:   make(map[uint32]Hits, len(nodes)),
+			Buckets: make(map[string]Hits),
+		}
+	)
+
+	for _, pivot := range pivots {
+		for _, n := range b.FindNodes(pivot, ss...) {
+			h := r.Nodes[n.N]
+			h.Actual++
+			r.Nodes[n.N] = h
+			total++
+		}
+	}
+
+	for _, w := range weights {
+		sum += w
+	}
+	for i, n := range nodes {
+		h := r.Nodes[n.N]
+		if sum == 0 {
+			h.Expected = float64(total) / float64(len(nodes))
+		} else {
+			h.Expected = float64(total) * weights[i] / sum
+		}
+		r.Nodes[n.N] = h
+	}
+
+	for _, c := range b.Children() {
+		r.collectBuckets("", c)
+	}
+
+	hits := make([]float64, 0, len(nodes))
+	load := make([]float64, 0, len(nodes))
+	for _, n := range nodes {
+		h := r.Nodes[n.N]
+		hits = append(hits, float64(h.Actual))
+		if h.Expected != 0 {
+			load = append(load, float64(h.Actual)/h.Expected)
+		}
+	}
+	r.Hits = NewStats(hits)
+	r.Load = NewStats(load)
+	return r
+}
+
+func (r *Report) collectBuckets(prefix string, b netmap.Bucket) {
+	var (
+		path = prefix + netmap.Separator + b.Name()
+		h    Hits
+	)
+
+	for _, n := range b.Nodelist() {
+		nh := r.Nodes[n.N]
+		h.Actual += nh.Actual
+		h.Expected += nh.Expected
+	}
+	r.Buckets[path] = h
+
+	for _, c := range b.Children() {
+		r.collectBuckets(path, c)
+	}
+}
+
+// NewStats calculates fairness metrics of values.
+func NewStats(values []float64) (s Stats) {
+	if len(values) == 0 {
+		return
+	}
+
+	var (
+		sum, max, sq, acc float64
+		n                 = float64(len(values))
+		sorted            = make([]float64, len(values))
+	)
+
+	copy(sorted, values)
+	sort.Float64s(sorted)
+	for i, v := range sorted {
+		sum += v
+		acc += float64(i+1) * v
+		if v > max {
+			max = v
+		}
+	}
+
+	s.Mean = sum / n
+	for _, v := range sorted {
+		sq += (v - s.Mean) * (v - s.Mean)
+	}
+	s.StdDev = math.Sqrt(sq / n)
+
+	if sum != 0 {
+		s.MaxMean = max / s.Mean
+		s.Gini = 2*acc/(n*sum) - (n+1)/n
+	}
+	return
+}
@@ -0,0 +1,65 @@
+package simulation
+
+import (
+	"testing"
+
+	"github.com/nspcc-dev/netmap"
+	"github.com/stretchr/testify/require"
+)
+
+const eps float64 = 0.001
+
+func TestNewStats(t *testing.T) {
+	var s Stats
+
+	s = NewStats(nil)
+	require.Equal(t, Stats{}, s)
+
+	s = NewStats([]float64{5, 5, 5, 5})
+	require.InEpsilon(t, 5, s.Mean, eps)
+	require.Equal(t, 0.0, s.StdDev)
+	require.InEpsilon(t, 1, s.MaxMean, eps)
+	require.Equal(t, 0.0, s.Gini)
+
+	s = NewStats([]float64{0, 0, 0, 8})
+	require.InEpsilon(t, 2, s.Mean, eps)
+	require.InEpsilon(t, 3.464, s.StdDev, eps)
+	require.InEpsilon(t, 4, s.MaxMean, eps)
+	require.InEpsilon(t, 0.75, s.Gini, eps)
+}
+
+func TestRun(t *testing.T) {
+	var (
+		b      netmap.Bucket
+		pivots = netmap.SamplePivots(2000)
+		ss     = netmap.SFGroup{Selectors: []netmap.Select{
+			{Key: "Country", Count: 1},
+			{Key: netmap.NodesBucket, Count: 1},
+		}}
+	)
+
+	require.NoError(t, b.AddBucket("/Location:Europe/Country:Germany", netmap.Nodes{{N: 1, C: 10}, {N: 2, C: 10}}))
+	require.NoError(t, b.AddBucket("/Location:Europe/Country:Austria", netmap.Nodes{{N: 3, C: 10}, {N: 4, C: 10}}))
+
+	r := Run(b, pivots, ss)
+	require.Equal(t, 2000, r.Pivots)
+	require.Len(t, r.Nodes, 4)
+
+	var total int
+	for _, h := range r.Nodes {
+		require.InEpsilon(t, 500, h.Expected, eps)
+		require.True(t, h.Actual > 0)
+		total += h.Actual
+	}
+	require.Equal(t, 2000, total)
+
+	require.Len(t, r.Buckets, 3)
+	require.Equal(t, 2000, r.Buckets["/Location:Europe"].Actual)
+	require.InEpsilon(t, 1000, r.Buckets["/Location:Europe/Country:Germany"].Expected, eps)
+	require.Equal(t, 2000, r.Buckets["/Location:Europe/Country:Germany"].Actual+r.Buckets["/Location:Europe/Country:Austria"].Actual)
+
+	require.InEpsilon(t, 500, r.Hits.Mean, eps)
+	require.InEpsilon(t, 1, r.Load.Mean, eps)
+	require.True(t, r.Hits.MaxMean < 1.2)
+	require.True(t, r.Hits.Gini < 0.1)
+}
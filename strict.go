@@ -0,0 +1,148 @@
+package netmap
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Validate checks if sf can be evaluated: operation must be known,
+// OR, AND and NOT must have arguments, patterns must be valid and
+// operand must be parsable according to sf.Type.
+func (sf SimpleFilter) Validate() error {
+	return sf.validate(false)
+}
+
+// validate checks sf. If numeric is set, sf is applied to numbers,
+// so operand must be numeric for every operation.
+func (sf SimpleFilter) validate(numeric bool) error {
+	switch sf.Op {
+	case Operation_NP:
+		return nil
+	case Operation_OR, Operation_AND, Operation_NOT:
+		args := sf.GetFArgs()
+		if args == nil {
+			return errors.Errorf("%s without arguments", sf.Op)
+		}
+		for _, f := range args.Filters {
+			if err := f.validate(numeric); err != nil {
+				return err
+			}
+		}
+		return nil
+	case Operation_EQ, Operation_NE:
+		if sf.Type == Type_String && !numeric {
+			return nil
+		}
+	case Operation_LIKE, Operation_REGEX, Operation_PREFIX, Operation_SUFFIX:
+		_, err := matchString(sf.Op, "", sf.GetValue())
+		return err
+	case Operation_GT, Operation_GE, Operation_LT, Operation_LE:
+	default:
+		return errors.Errorf("unknown operation %d", sf.Op)
+	}
+
+	if sf.GetFArgs() != nil {
+		return errors.Errorf("%s with filter arguments", sf.Op)
+	}
+
+	t := sf.Type
+	switch {
+	case numeric:
+		_, err := parseNumber(sf.GetValue(), t)
+		return err
+	case t == Type_String:
+		t = Type_Integer
+	}
+	_, err := compareValues(sf.GetValue(), sf.GetValue(), t)
+	return err
+}
+
+// Validate checks if f has a key and valid SimpleFilter.
+func (f Filter) Validate() error {
+	if f.Key == "" {
+		return errors.New("empty filter key")
+	}
+	if f.F == nil {
+		return errors.Errorf("filter %s: empty filter", f.Key)
+	}
+	if err := f.F.validate(strings.HasPrefix(f.Key, NodeFilterPrefix)); err != nil {
+		return errors.Wrapf(err, "filter %s", formatFilter(f))
+	}
+	return nil
+}
+
+// Validate checks if all filters, selects, limits and excluded buckets of s are valid.
+func (s SFGroup) Validate() error {
+	for i := range s.Filters {
+		if err := s.Filters[i].Validate(); err != nil {
+			return err
+		}
+	}
+	for i := range s.Selectors {
+		if s.Selectors[i].Key == "" {
+			return errors.New("empty select key")
+		}
+		if s.Selectors[i].Count == 0 {
+			return errors.Errorf("select %s: count must be positive", s.Selectors[i].Key)
+		}
+	}
+	for i := range s.Limits {
+		if s.Limits[i].Key == "" {
+			return errors.New("empty limit key")
+		}
+		if s.Limits[i].Max == 0 {
+			return errors.Errorf("limit %s: max must be positive", s.Limits[i].Key)
+		}
+	}
+	for _, o := range s.ExcludeBuckets {
+		if err := checkBucketPath(o); err != nil {
+			return errors.Wrapf(err, "exclude %q", o)
+		}
+	}
+	return nil
+}
+
+// Validate checks if all groups of r are valid.
+func (r PlacementRule) Validate() error {
+	for i := range r.SFGroups {
+		if err := r.SFGroups[i].Validate(); err != nil {
+			return errors.Wrapf(err, "group %d", i)
+		}
+	}
+	return nil
+}
+
+// GetMaxSelectionStrict is similar to GetMaxSelection, but
+// returns error if s is invalid or if some bucket value
+// can't be compared with the filter operand.
+func (b Bucket) GetMaxSelectionStrict(s SFGroup) (*Bucket, error) {
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+
+	allowed, err := b.findAllowedC(s.Filters, true)
+	if err != nil {
+		return nil, err
+	}
+
+	r, _ := b.getMaxSelection(s.Selectors, excludeFunc(allowed, b.excluded(s)), b.newLimiter(s.Limits))
+	return r, nil
+}
+
+// FindNodesStrict is similar to FindNodes, but filters are evaluated
+// as in GetMaxSelectionStrict.
+func (b *Bucket) FindNodesStrict(pivot []byte, ss ...SFGroup) (nodes Nodes, err error) {
+	for _, s := range ss {
+		var c *Bucket
+		if c, err = b.GetMaxSelectionStrict(s); err != nil {
+			return nil, err
+		} else if c == nil {
+			continue
+		}
+		if c = c.GetSelectionWithLimits(s.Selectors, s.Limits, pivot); c != nil {
+			nodes = merge(nodes, c.Nodelist())
+		}
+	}
+	return
+}
@@ -0,0 +1,107 @@
+package netmap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSFGroup_Validate(t *testing.T) {
+	valid := []Filter{
+		{Key: "Country", F: FilterEQ("Germany")},
+		{Key: "Trust", F: FilterGT(10)},
+		{Key: "Trust", F: FilterIn("1", "2")},
+		{Key: "Version", F: NewTypedFilter(Operation_GE, Type_Version, "v1.2.0")},
+		{Key: CapacityFilterKey, F: NewTypedFilter(Operation_GE, Type_Size, "1TB")},
+		{Key: NodeFilterKey("uptime"), F: NewFilter(Operation_GE, "0.99")},
+		{Key: "Any", F: &SimpleFilter{Op: Operation_NP}},
+	}
+	for _, f := range valid {
+		require.NoError(t, f.Validate(), f.String())
+	}
+
+	invalid := []struct {
+		f   Filter
+		err string
+	}{
+		{Filter{Key: "Trust", F: NewFilter(Operation_GT, "ten")}, `filter Trust GT ten: can't parse "ten" as integer`},
+		{Filter{Key: "Trust", F: NewFilter(Operation(42), "1")}, `filter Trust 42 1: unknown operation 42`},
+		{Filter{Key: "Trust", F: &SimpleFilter{Op: Operation_AND}}, `filter Trust AND: AND without arguments`},
+		{Filter{Key: "Trust", F: FilterOR(FilterEQ("1"), FilterGE(1), NewFilter(Operation_LT, "x"))}, `filter Trust EQ 1 OR Trust GE 1 OR Trust LT x: can't parse "x" as integer`},
+		{Filter{Key: NodeFilterKey("C"), F: FilterEQ("big")}, `filter Node.C EQ big: can't parse "big" as float`},
+		{Filter{Key: NodeFilterKey("C"), F: NewTypedFilter(Operation_EQ, Type_Version, "1.0")}, `filter Node.C EQ 1.0 AS Version: Version is not a numeric type`},
+		{Filter{Key: "", F: FilterEQ("1")}, `empty filter key`},
+		{Filter{Key: "Trust"}, `filter Trust: empty filter`},
+	}
+	for _, c := range invalid {
+		require.EqualError(t, c.f.Validate(), c.err)
+	}
+
+	require.NoError(t, SFGroup{
+		Filters:        valid,
+		Selectors:      []Select{{Key: "Country", Count: 1}},
+		Limits:         []Limit{{Key: "City", Max: 1}},
+		ExcludeBuckets: []string{"/Country:Russia", "/Location:Asia/Country:China"},
+	}.Validate())
+
+	require.EqualError(t, SFGroup{Selectors: []Select{{Key: "Country"}}}.Validate(), "select Country: count must be positive")
+	require.EqualError(t, SFGroup{Selectors: []Select{{Count: 1}}}.Validate(), "empty select key")
+	require.EqualError(t, SFGroup{Limits: []Limit{{Max: 1}}}.Validate(), "empty limit key")
+	require.EqualError(t, SFGroup{ExcludeBuckets: []string{"Country:Russia"}}.Validate(), `exclude "Country:Russia": path must start with /`)
+	require.EqualError(t, SFGroup{ExcludeBuckets: []string{"/Country"}}.Validate(), `exclude "/Country": bucket "Country": wrong format`)
+	require.EqualError(t, SFGroup{ExcludeBuckets: []string{"/"}}.Validate(), `exclude "/": root bucket is not allowed`)
+	require.EqualError(t, SFGroup{ExcludeBuckets: []string{""}}.Validate(), `exclude "": empty path`)
+	require.EqualError(t, SFGroup{ExcludeBuckets: []string{"/Country:"}}.Validate(), `exclude "/Country:": bucket "Country:": empty key or value`)
+
+	require.EqualError(t, PlacementRule{SFGroups: []SFGroup{{}, {Filters: []Filter{invalid[0].f}}}}.Validate(),
+		`group 1: filter Trust GT ten: can't parse "ten" as integer`)
+}
+
+func TestBucket_FindNodesStrict(t *testing.T) {
+	root, err := newRoot(
+		bucket{"/Location:Europe/Trust:10", []uint32{1, 2}},
+		bucket{"/Location:Europe/Trust:20", []uint32{3}},
+		bucket{"/Location:Asia/Trust:high", []uint32{4}},
+	)
+	require.NoError(t, err)
+
+	s := SFGroup{
+		Filters:   []Filter{{Key: "Trust", F: NewFilter(Operation_GT, "ten")}},
+		Selectors: []Select{{Key: NodesBucket, Count: 1}},
+	}
+
+	// typo in operand silently allows every node
+	require.Equal(t, root.Nodelist(), root.GetMaxSelection(s).Nodelist())
+
+	_, err = root.GetMaxSelectionStrict(s)
+	require.EqualError(t, err, `filter Trust GT ten: can't parse "ten" as integer`)
+	_, err = root.FindNodesStrict(nil, s)
+	require.Error(t, err)
+
+	// unparsable bucket values are errors too
+	s.Filters = []Filter{{Key: "Trust", F: FilterGT(15)}}
+	require.Equal(t, []uint32{3, 4}, root.GetMaxSelection(s).Nodelist().Nodes())
+	_, err = root.GetMaxSelectionStrict(s)
+	require.EqualError(t, err, `filter Trust GT 15 on bucket Trust:high: can't parse "high" as integer`)
+
+	s.Filters = []Filter{
+		{Key: "Location", F: FilterEQ("Europe")},
+		{Key: "Trust", F: FilterGT(15)},
+	}
+	_, err = root.GetMaxSelectionStrict(s)
+	require.Error(t, err)
+
+	s.Filters = []Filter{{Key: "Location", F: FilterEQ("Europe")}}
+	r, err := root.GetMaxSelectionStrict(s)
+	require.NoError(t, err)
+	require.Equal(t, []uint32{1, 2, 3}, r.Nodelist().Nodes())
+
+	nodes, err := root.FindNodesStrict(defaultPivot, s)
+	require.NoError(t, err)
+	require.Equal(t, root.FindNodes(defaultPivot, s), nodes)
+
+	s.Selectors[0].Count = 4
+	r, err = root.GetMaxSelectionStrict(s)
+	require.NoError(t, err)
+	require.Nil(t, r)
+}
@@ -0,0 +1,210 @@
+package netmap
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+// Text netmap format consists of lines
+//
+//	add <N> [C=<capacity>] [P=<price>] [<metric>=<value> ...] [/K1:V1/K2:V2 ...]
+//
+// where every option is a path in the same format as in AddNode.
+// Node can be added in several lines, its options are merged then.
+// Empty lines and lines starting with '#' are ignored, arguments containing
+// spaces can be double-quoted. Bucket weights are not stored.
+// 'save <name>' lines are ignored too, so that REPL scripts consisting of
+// 'add' commands followed by 'save' (like examples/*.input) can be read.
+
+// WriteText writes b in text format, one line per node in order of N.
+func (b Bucket) WriteText(w io.Writer) error {
+	opts := b.nodeOptions()
+
+	bw := bufio.NewWriter(w)
+	for _, n := range b.Nodelist() {
+		line := []string{"add", strconv.FormatUint(uint64(n.N), 10)}
+		if n.C != 0 {
+			line = append(line, "C="+strconv.FormatUint(n.C, 10))
+		}
+		if n.P != 0 {
+			line = append(line, "P="+strconv.FormatUint(n.P, 10))
+		}
+
+		for _, name := range n.M.Names() {
+			line = append(line, quoteField(name+"="+strconv.FormatFloat(n.Metric(name), 'g', -1, 64)))
+		}
+
+		for _, o := range opts[n.N] {
+			line = append(line, quoteField(o))
+		}
+		if _, err := bw.WriteString(strings.Join(line, " ") + "\n"); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// ReadText reads b in text format.
+func (b *Bucket) ReadText(r io.Reader) error {
+	var (
+		nodes = make(map[uint32]*NodeOptions)
+		order []uint32
+		s     = bufio.NewScanner(r)
+	)
+
+	for line := 1; s.Scan(); line++ {
+		fields, err := splitFields(s.Text())
+		if err != nil {
+			return errors.Wrapf(err, "line %d", line)
+		}
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if fields[0] == "save" && len(fields) == 2 {
+			continue
+		}
+		if fields[0] != "add" || len(fields) < 2 {
+			return errors.Errorf("line %d: expected 'add <N> [attributes] [options]'", line)
+		}
+
+		n, err := strconv.ParseUint(fields[1], 10, 32)
+		if err != nil {
+			return errors.Errorf("line %d: invalid node number %q", line, fields[1])
+		}
+		node, ok := nodes[uint32(n)]
+		if !ok {
+			node = &NodeOptions{Node: Node{N: uint32(n)}}
+			nodes[uint32(n)] = node
+			order = append(order, uint32(n))
+		}
+
+		for _, f := range fields[2:] {
+			if strings.HasPrefix(f, Separator) {
+				node.Options = append(node.Options, f)
+				continue
+			}
+			if err = setAttribute(&node.Node, f); err != nil {
+				return errors.Wrapf(err, "line %d", line)
+			}
+		}
+	}
+	if err := s.Err(); err != nil {
+		return err
+	}
+
+	var nb Bucket
+	sort.Slice(order, func(i, j int) bool { return order[i] < order[j] })
+	for _, n := range order {
+		node := nodes[n]
+		if len(node.Options) == 0 {
+			nb.nodes = merge(nb.nodes, Nodes{node.Node})
+			continue
+		}
+		if err := nb.AddStrawNode(node.Node, node.Options...); err != nil {
+			return errors.Wrapf(err, "node %d", n)
+		}
+	}
+	*b = nb
+	return nil
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (b Bucket) MarshalText() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := b.WriteText(buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (b *Bucket) UnmarshalText(data []byte) error {
+	return b.ReadText(bytes.NewReader(data))
+}
+
+// setAttribute sets capacity, price or metric of n from "name=value" string.
+// Attribute which is already set must have the same value.
+func setAttribute(n *Node, attr string) error {
+	i := strings.IndexByte(attr, '=')
+	if i <= 0 {
+		return errors.Errorf("invalid attribute %q", attr)
+	}
+
+	name, value := attr[:i], attr[i+1:]
+	switch name {
+	case "C", "P":
+		v, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return errors.Errorf("invalid %s value %q", name, value)
+		}
+		p := &n.C
+		if name == "P" {
+			p = &n.P
+		}
+		if *p != 0 && *p != v {
+			return errors.Errorf("conflicting %s value %q", name, value)
+		}
+		*p = v
+	default:
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return errors.Errorf("invalid metric %s value %q", name, value)
+		}
+		if old, ok := n.M.Get(name); ok && old != v {
+			return errors.Errorf("conflicting metric %s value %q", name, value)
+		}
+		n.M = n.M.With(name, v)
+	}
+	return nil
+}
+
+// splitFields splits line by spaces taking double-quoted fields into account.
+func splitFields(line string) ([]string, error) {
+	var fields []string
+
+	for line = strings.TrimSpace(line); line != ""; line = strings.TrimLeftFunc(line, unicode.IsSpace) {
+		if line[0] != '"' {
+			i := strings.IndexFunc(line, unicode.IsSpace)
+			if i < 0 {
+				i = len(line)
+			}
+			fields = append(fields, line[:i])
+			line = line[i:]
+			continue
+		}
+
+		i := 1
+		for ; i < len(line) && line[i] != '"'; i++ {
+			if line[i] == '\\' {
+				i++
+			}
+		}
+		if i >= len(line) {
+			return nil, errors.New("unterminated string")
+		}
+		f, err := strconv.Unquote(line[:i+1])
+		if err != nil {
+			return nil, errors.Errorf("invalid string %s", line[:i+1])
+		}
+		fields = append(fields, f)
+		line = line[i+1:]
+	}
+	return fields, nil
+}
+
+// quoteField quotes s if it contains spaces or quotes.
+func quoteField(s string) string {
+	if strings.IndexFunc(s, func(r rune) bool {
+		return unicode.IsSpace(r) || r == '"' || !unicode.IsPrint(r)
+	}) >= 0 || strings.HasPrefix(s, "#") {
+		return strconv.Quote(s)
+	}
+	return s
+}
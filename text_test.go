@@ -0,0 +1,102 @@
+package netmap
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBucket_MarshalText(t *testing.T) {
+	b, err := newStrawRoot(
+		strawBucket{"/Location:Europe/Country:Germany", Nodes{
+			{N: 1, C: 10, P: 1, M: NewMetrics(map[string]float64{"uptime": 0.99, "load": 0.5})},
+			{N: 2, C: 20},
+		}},
+		strawBucket{"/Location:Asia/City:Hong Kong", Nodes{{N: 3, C: 30, P: 3}}},
+	)
+	require.NoError(t, err)
+	b.nodes = merge(b.nodes, Nodes{{N: 4}})
+
+	data, err := b.MarshalText()
+	require.NoError(t, err)
+	require.Equal(t, `add 1 C=10 P=1 load=0.5 uptime=0.99 /Location:Europe/Country:Germany
+add 2 C=20 /Location:Europe/Country:Germany
+add 3 C=30 P=3 "/Location:Asia/City:Hong Kong"
+add 4
+`, string(data))
+
+	var nb Bucket
+	require.NoError(t, nb.UnmarshalText(data))
+	require.True(t, Diff(b, nb).Empty())
+	require.Equal(t, b.Nodelist(), nb.Nodelist())
+	require.Equal(t, []string{"/Location:Asia/City:Hong Kong"}, nb.GetOptionsByNode(3))
+
+	t.Run("empty", func(t *testing.T) {
+		data, err := Bucket{}.MarshalText()
+		require.NoError(t, err)
+		require.Empty(t, data)
+
+		var nb Bucket
+		require.NoError(t, nb.UnmarshalText(data))
+		require.Equal(t, Bucket{}, nb)
+	})
+}
+
+func TestBucket_ReadText(t *testing.T) {
+	t.Run("merge lines", func(t *testing.T) {
+		var b Bucket
+		require.NoError(t, b.UnmarshalText([]byte(`
+# storage nodes
+add 2 /Location:Europe/Country:Germany
+add 1 C=10 /Location:Europe/Country:France
+add 1 P=5 uptime=1  /Type:SSD
+add 2 "/Type:HDD"
+`)))
+		require.Equal(t, Nodes{{N: 1, C: 10, P: 5, M: NewMetrics(map[string]float64{"uptime": 1})}, {N: 2}}, b.Nodelist())
+		require.ElementsMatch(t, []string{"/Location:Europe/Country:France", "/Type:SSD"}, b.GetOptionsByNode(1))
+		require.ElementsMatch(t, []string{"/Location:Europe/Country:Germany", "/Type:HDD"}, b.GetOptionsByNode(2))
+	})
+
+	t.Run("examples", func(t *testing.T) {
+		for _, name := range []string{"examples/demo", "examples/map2"} {
+			script, err := ioutil.ReadFile(name + ".input")
+			require.NoError(t, err)
+
+			var b Bucket
+			require.NoError(t, b.UnmarshalText(script), name)
+			require.NotEmpty(t, b.Nodelist(), name)
+			require.NoError(t, b.Validate(), name)
+
+			data, err := ioutil.ReadFile(name)
+			require.NoError(t, err)
+
+			var saved Bucket
+			require.NoError(t, saved.UnmarshalBinary(data), name)
+			require.Equal(t, saved.Nodelist(), b.Nodelist(), name)
+			for _, n := range b.Nodelist() {
+				require.ElementsMatch(t, saved.GetOptionsByNode(n.N), b.GetOptionsByNode(n.N), name)
+			}
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, c := range []struct{ input, err string }{
+			{"add", "line 1"},
+			{"\nremove 1 /Location:US", "line 2"},
+			{"save", "line 1"},
+			{"add x /Location:US", "invalid node number"},
+			{"add 1 C=x", "invalid C value"},
+			{"add 1 uptime=high", "invalid metric"},
+			{"add 1 C=1\nadd 1 C=2", "line 2: conflicting C value"},
+			{"add 1 \"/Location:US", "unterminated string"},
+			{"add 1 /Location:US/", "node 1"},
+		} {
+			var b Bucket
+			err := b.ReadText(strings.NewReader(c.input))
+			require.Error(t, err, c.input)
+			require.Contains(t, err.Error(), c.err, c.input)
+		}
+	})
+}
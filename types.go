@@ -0,0 +1,268 @@
+package netmap
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// sizeUnits maps size unit to the number of bytes in it.
+var sizeUnits = map[string]float64{
+	"":    1,
+	"B":   1,
+	"KB":  1e3,
+	"MB":  1e6,
+	"GB":  1e9,
+	"TB":  1e12,
+	"PB":  1e15,
+	"KIB": 1 << 10,
+	"MIB": 1 << 20,
+	"GIB": 1 << 30,
+	"TIB": 1 << 40,
+	"PIB": 1 << 50,
+}
+
+// Match returns result of applying sf to value, where both value and
+// sf operand are parsed according to sf.Type.
+// Unlike Check, error is returned if value or operand can't be parsed
+// or operation is unknown.
+// For String type EQ and NE compare strings and other operations compare integers.
+// LIKE, REGEX, PREFIX and SUFFIX always operate on strings.
+func (sf SimpleFilter) Match(value string) (bool, error) {
+	switch sf.Op {
+	case Operation_NP:
+		return true, nil
+	case Operation_OR, Operation_AND:
+		args := sf.GetFArgs()
+		if args == nil {
+			return false, errors.Errorf("%s without arguments", sf.Op)
+		}
+		for _, f := range args.Filters {
+			ok, err := f.Match(value)
+			if err != nil {
+				return false, err
+			}
+			if ok == (sf.Op == Operation_OR) {
+				return ok, nil
+			}
+		}
+		return sf.Op == Operation_AND, nil
+	case Operation_NOT:
+		args := sf.GetFArgs()
+		if args == nil {
+			return false, errors.Errorf("%s without arguments", sf.Op)
+		}
+		for _, f := range args.Filters {
+			ok, err := f.Match(value)
+			if err != nil || !ok {
+				return err == nil, err
+			}
+		}
+		return false, nil
+	case Operation_LIKE, Operation_REGEX, Operation_PREFIX, Operation_SUFFIX:
+		return matchString(sf.Op, value, sf.GetValue())
+	case Operation_EQ, Operation_NE, Operation_GT, Operation_GE, Operation_LT, Operation_LE:
+	default:
+		return false, errors.Errorf("unknown operation %d", sf.Op)
+	}
+
+	t := sf.Type
+	if t == Type_String {
+		if sf.Op == Operation_EQ || sf.Op == Operation_NE {
+			return (value == sf.GetValue()) == (sf.Op == Operation_EQ), nil
+		}
+		t = Type_Integer
+	}
+
+	c, err := compareValues(value, sf.GetValue(), t)
+	if err != nil {
+		return false, err
+	}
+
+	switch sf.Op {
+	case Operation_EQ:
+		return c == 0, nil
+	case Operation_NE:
+		return c != 0, nil
+	case Operation_GT:
+		return c > 0, nil
+	case Operation_GE:
+		return c >= 0, nil
+	case Operation_LT:
+		return c < 0, nil
+	default:
+		return c <= 0, nil
+	}
+}
+
+// compareValues parses a and b as t and returns -1, 0 or 1
+// if a is less, equal or greater than b respectively.
+func compareValues(a, b string, t Type) (int, error) {
+	if t == Type_String {
+		return strings.Compare(a, b), nil
+	}
+	if t == Type_Version {
+		va, err := parseVersion(a)
+		if err != nil {
+			return 0, err
+		}
+		vb, err := parseVersion(b)
+		if err != nil {
+			return 0, err
+		}
+		return va.compare(vb), nil
+	}
+
+	x, err := parseNumber(a, t)
+	if err != nil {
+		return 0, err
+	}
+	y, err := parseNumber(b, t)
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case x < y:
+		return -1, nil
+	case x > y:
+		return 1, nil
+	default:
+		return 0, nil
+	}
+}
+
+// parseNumber parses s as numeric value of type t.
+// Durations are returned in nanoseconds and sizes in bytes.
+func parseNumber(s string, t Type) (float64, error) {
+	switch t {
+	case Type_Integer:
+		v, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return 0, errors.Errorf("can't parse %q as integer", s)
+		}
+		return float64(v), nil
+	case Type_String, Type_Float:
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil || math.IsNaN(v) {
+			return 0, errors.Errorf("can't parse %q as float", s)
+		}
+		return v, nil
+	case Type_Duration:
+		v, err := time.ParseDuration(s)
+		if err != nil {
+			return 0, errors.Errorf("can't parse %q as duration", s)
+		}
+		return float64(v), nil
+	case Type_Size:
+		return parseSize(s)
+	default:
+		return 0, errors.Errorf("%s is not a numeric type", t)
+	}
+}
+
+// parseSize parses size with optional unit, e.g. "10GB" or "1.5GiB", to bytes.
+func parseSize(s string) (float64, error) {
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i < 0 {
+		i = len(s)
+	}
+
+	v, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil {
+		return 0, errors.Errorf("can't parse %q as size", s)
+	}
+	unit, ok := sizeUnits[strings.ToUpper(strings.TrimSpace(s[i:]))]
+	if !ok {
+		return 0, errors.Errorf("can't parse %q as size: unknown unit %q", s, s[i:])
+	}
+	return v * unit, nil
+}
+
+// version is a parsed semantic version.
+type version struct {
+	nums [3]uint64
+	pre  []string
+}
+
+// parseVersion parses semantic version with optional "v" prefix.
+// Missing minor and patch numbers are treated as 0, build metadata is ignored.
+func parseVersion(s string) (v version, err error) {
+	str := strings.TrimPrefix(s, "v")
+	if i := strings.IndexByte(str, '+'); i >= 0 {
+		str = str[:i]
+	}
+	if i := strings.IndexByte(str, '-'); i >= 0 {
+		if v.pre = strings.Split(str[i+1:], "."); str[i+1:] == "" {
+			return v, errors.Errorf("can't parse %q as version", s)
+		}
+		str = str[:i]
+	}
+
+	nums := strings.Split(str, ".")
+	if len(nums) > len(v.nums) {
+		return v, errors.Errorf("can't parse %q as version", s)
+	}
+	for i := range nums {
+		if v.nums[i], err = strconv.ParseUint(nums[i], 10, 64); err != nil {
+			return v, errors.Errorf("can't parse %q as version", s)
+		}
+	}
+	return v, nil
+}
+
+// compare compares versions according to semver precedence rules.
+func (v version) compare(o version) int {
+	for i := range v.nums {
+		switch {
+		case v.nums[i] < o.nums[i]:
+			return -1
+		case v.nums[i] > o.nums[i]:
+			return 1
+		}
+	}
+
+	switch {
+	case len(v.pre) == 0 && len(o.pre) == 0:
+		return 0
+	case len(v.pre) == 0:
+		return 1
+	case len(o.pre) == 0:
+		return -1
+	}
+
+	for i := 0; i < len(v.pre) && i < len(o.pre); i++ {
+		a, errA := strconv.ParseUint(v.pre[i], 10, 64)
+		b, errB := strconv.ParseUint(o.pre[i], 10, 64)
+		switch {
+		case errA == nil && errB == nil:
+			if a != b {
+				if a < b {
+					return -1
+				}
+				return 1
+			}
+		case errA == nil:
+			return -1
+		case errB == nil:
+			return 1
+		default:
+			if c := strings.Compare(v.pre[i], o.pre[i]); c != 0 {
+				return c
+			}
+		}
+	}
+
+	switch {
+	case len(v.pre) < len(o.pre):
+		return -1
+	case len(v.pre) > len(o.pre):
+		return 1
+	default:
+		return 0
+	}
+}
@@ -1,5 +1,7 @@
 package netmap
 
+import "sort"
+
 func getNodes(b Bucket, path []Bucket) (nodes Nodes) {
 	if len(path) == 0 {
 		return b.Nodelist()
@@ -21,6 +23,15 @@ func contains(nodes Nodes, n Node) bool {
 	return false
 }
 
+// search returns index of node n in sorted nodes or len(nodes) if n is missing.
+func search(nodes Nodes, n uint32) int {
+	i := sort.Search(len(nodes), func(i int) bool { return nodes[i].N >= n })
+	if i < len(nodes) && nodes[i].N == n {
+		return i
+	}
+	return len(nodes)
+}
+
 func intersect(a, b Nodes) Nodes {
 	if a == nil {
 		return b
@@ -0,0 +1,117 @@
+package netmap
+
+import (
+	"fmt"
+	"strings"
+)
+
+type (
+	// Issue describes single problem of a malformed Bucket.
+	Issue struct {
+		// Path is the full path to the offending bucket.
+		Path string
+		// Node is the offending node if HasNode is set.
+		Node    uint32
+		HasNode bool
+		Reason  string
+	}
+
+	// ValidationError contains all issues found in a Bucket.
+	ValidationError []Issue
+
+	// nodeKey identifies node under the buckets with the same key.
+	nodeKey struct {
+		key string
+		n   uint32
+	}
+)
+
+func (i Issue) Error() string {
+	path := i.Path
+	if path == "" {
+		path = Separator
+	}
+	if i.HasNode {
+		return fmt.Sprintf("%s: node %d: %s", path, i.Node, i.Reason)
+	}
+	return fmt.Sprintf("%s: %s", path, i.Reason)
+}
+
+func (e ValidationError) Error() string {
+	ss := make([]string, 0, len(e))
+	for i := range e {
+		ss = append(ss, e[i].Error())
+	}
+	return "invalid bucket: " + strings.Join(ss, "; ")
+}
+
+// Validate checks if bucket is well-formed and returns ValidationError
+// describing all found issues:
+// - nodes must be sorted and unique;
+// - all nodes contained in sub-bucket must belong to this;
+// - there must be no nodes belonging to 2 sibling buckets with the same key;
+// - there must be no sibling buckets with the same name;
+// - key and value of every sub-bucket must be non-empty.
+// Unlike IsValid, it rejects unsorted nodes, duplicate and unnamed buckets.
+func (b Bucket) Validate() error {
+	var e ValidationError
+	b.validate("", &e)
+	if len(e) != 0 {
+		return e
+	}
+	return nil
+}
+
+func (b Bucket) validate(path string, e *ValidationError) {
+	if path != "" {
+		if b.Key == "" {
+			*e = append(*e, Issue{Path: path, Reason: "empty key"})
+		}
+		if b.Value == "" {
+			*e = append(*e, Issue{Path: path, Reason: "empty value"})
+		}
+	}
+
+	for i := 1; i < len(b.nodes); i++ {
+		if b.nodes[i-1].N >= b.nodes[i].N {
+			*e = append(*e, Issue{Path: path, Node: b.nodes[i].N, HasNode: true, Reason: "nodes are not sorted"})
+			break
+		}
+	}
+
+	if len(b.children) == 0 {
+		return
+	}
+
+	var (
+		names  = make(map[string]bool, len(b.children))
+		owners = make(map[nodeKey]string)
+		nodes  = make(map[uint32]bool, len(b.nodes))
+	)
+
+	for _, n := range b.nodes {
+		nodes[n.N] = true
+	}
+
+	for _, c := range b.children {
+		cpath := path + Separator + c.Name()
+		if names[c.Name()] {
+			*e = append(*e, Issue{Path: cpath, Reason: "duplicate bucket"})
+		}
+		names[c.Name()] = true
+
+		for _, n := range c.nodes {
+			if !nodes[n.N] {
+				*e = append(*e, Issue{Path: cpath, Node: n.N, HasNode: true, Reason: "node is missing in parent"})
+			}
+			k := nodeKey{key: c.Key, n: n.N}
+			if owner, ok := owners[k]; ok {
+				*e = append(*e, Issue{Path: cpath, Node: n.N, HasNode: true, Reason: "node also belongs to " + owner})
+			} else {
+				owners[k] = cpath
+			}
+		}
+
+		c.validate(cpath, e)
+	}
+}
@@ -0,0 +1,97 @@
+package netmap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBucket_Validate(t *testing.T) {
+	var (
+		b   Bucket
+		err error
+	)
+
+	b, err = newRoot(
+		bucket{"/Location:Europe/Country:Germany", []uint32{1, 3}},
+		bucket{"/Location:Asia/Country:China", []uint32{2}},
+	)
+	require.NoError(t, err)
+	require.NoError(t, b.Validate())
+
+	t.Run("node missing in parent", func(t *testing.T) {
+		b = Bucket{
+			nodes: Nodes{{N: 1}},
+			children: []Bucket{{
+				Key:   "Location",
+				Value: "Europe",
+				nodes: Nodes{{N: 1}, {N: 2}},
+			}},
+		}
+		err = b.Validate()
+		require.Error(t, err)
+		require.Equal(t, ValidationError{
+			{Path: "/Location:Europe", Node: 2, HasNode: true, Reason: "node is missing in parent"},
+		}, err)
+		require.EqualError(t, err, "invalid bucket: /Location:Europe: node 2: node is missing in parent")
+	})
+
+	t.Run("node in sibling buckets", func(t *testing.T) {
+		b, err = newRoot(
+			bucket{"/Location:Europe/Country:Germany", []uint32{1, 3}},
+			bucket{"/Location:Europe/Country:France", []uint32{3}},
+		)
+		require.NoError(t, err)
+		require.Equal(t, ValidationError{{
+			Path:    "/Location:Europe/Country:France",
+			Node:    3,
+			HasNode: true,
+			Reason:  "node also belongs to /Location:Europe/Country:Germany",
+		}}, b.Validate())
+	})
+
+	t.Run("node under different keys", func(t *testing.T) {
+		b = Bucket{}
+		require.NoError(t, b.AddNode(1, "/Location:Europe/Country:DE", "/Trust:10"))
+		require.NoError(t, b.AddNode(2, "/Location:Europe/Country:FR", "/Trust:10"))
+		require.NoError(t, b.Validate())
+	})
+
+	t.Run("unsorted nodes", func(t *testing.T) {
+		b = Bucket{nodes: Nodes{{N: 1}, {N: 3}, {N: 2}}}
+		require.Equal(t, ValidationError{
+			{Path: "", Node: 2, HasNode: true, Reason: "nodes are not sorted"},
+		}, b.Validate())
+		require.EqualError(t, b.Validate(), "invalid bucket: /: node 2: nodes are not sorted")
+	})
+
+	t.Run("empty key and value", func(t *testing.T) {
+		b = Bucket{children: []Bucket{{Key: "Location"}, {Value: "Europe"}}}
+		require.Equal(t, ValidationError{
+			{Path: "/Location:", Reason: "empty value"},
+			{Path: "/:Europe", Reason: "empty key"},
+		}, b.Validate())
+		require.True(t, b.IsValid(), "IsValid checks only structure")
+	})
+
+	t.Run("root option", func(t *testing.T) {
+		var b Bucket
+		require.NoError(t, b.AddBucket("/", Nodes{{N: 1}}))
+		require.True(t, b.IsValid())
+		require.Error(t, b.Validate())
+	})
+
+	t.Run("duplicate children", func(t *testing.T) {
+		b = Bucket{
+			nodes: Nodes{{N: 1}, {N: 2}},
+			children: []Bucket{
+				{Key: "Location", Value: "Europe", nodes: Nodes{{N: 1}}},
+				{Key: "Location", Value: "Europe", nodes: Nodes{{N: 2}}},
+			},
+		}
+		require.Equal(t, ValidationError{
+			{Path: "/Location:Europe", Reason: "duplicate bucket"},
+		}, b.Validate())
+		require.True(t, b.IsValid(), "IsValid checks only structure")
+	})
+}
@@ -5,6 +5,14 @@ type (
 	AggregatorFactory struct {
 		New func() Aggregator
 	}
+
+	// WeightTerm pairs node metric with its own normalizer.
+	// Factor is used only when terms are combined by weighted sum.
+	WeightTerm struct {
+		Metric WeightFunc
+		Norm   Normalizer
+		Factor float64
+	}
 )
 
 // CapWeightFunc calculates weight which is equal to capacity.
@@ -13,12 +21,42 @@ func CapWeightFunc(n Node) float64 { return float64(n.C) }
 // PriceWeightFunc calculates weight which is equal to price.
 func PriceWeightFunc(n Node) float64 { return float64(n.P) }
 
+// MetricWeightFunc returns WeightFunc which calculates weight
+// equal to value of the metric name.
+func MetricWeightFunc(name string) WeightFunc {
+	return func(n Node) float64 { return n.Metric(name) }
+}
+
 // NewWeightFunc returns WeightFunc which multiplies normalized
 // capacity and price.
-// TODO generic solution for arbitrary number of weights
 func NewWeightFunc(capNorm, priceNorm Normalizer) WeightFunc {
+	return NewProductWeightFunc(
+		WeightTerm{Metric: CapWeightFunc, Norm: capNorm},
+		WeightTerm{Metric: PriceWeightFunc, Norm: priceNorm},
+	)
+}
+
+// NewProductWeightFunc returns WeightFunc which multiplies
+// all normalized metrics.
+func NewProductWeightFunc(ts ...WeightTerm) WeightFunc {
+	return func(n Node) float64 {
+		w := 1.0
+		for i := range ts {
+			w *= ts[i].Norm.Normalize(ts[i].Metric(n))
+		}
+		return w
+	}
+}
+
+// NewSumWeightFunc returns WeightFunc which calculates sum
+// of normalized metrics multiplied by their factors.
+func NewSumWeightFunc(ts ...WeightTerm) WeightFunc {
 	return func(n Node) float64 {
-		return capNorm.Normalize(float64(n.C)) * priceNorm.Normalize(float64(n.P))
+		var w float64
+		for i := range ts {
+			w += ts[i].Factor * ts[i].Norm.Normalize(ts[i].Metric(n))
+		}
+		return w
 	}
 }
 